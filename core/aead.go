@@ -0,0 +1,104 @@
+// aead.go - Authenticated encryption keyed from QALX keys
+package coherra
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha512"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// AEADAlgorithm selects the authenticated cipher used by QALXSeal and QALXOpen.
+type AEADAlgorithm string
+
+const (
+	AEADAES256GCM        AEADAlgorithm = "aes-256-gcm"
+	AEADChaCha20Poly1305 AEADAlgorithm = "chacha20-poly1305"
+)
+
+// MinAEADKeySize is the minimum QALX key length, in bytes, accepted for encryption.
+const MinAEADKeySize = 32
+
+const aeadKeyDomain = "QALX-AEAD-v1"
+
+var (
+	ErrUnknownAEADAlgorithm = &QALXError{"Unknown AEAD algorithm"}
+	ErrAEADKeyTooShort      = &QALXError{"QALX key too short for authenticated encryption"}
+	ErrCiphertextTooShort   = &QALXError{"Ciphertext too short"}
+	ErrDecryptionFailed     = &QALXError{"Message authentication failed"}
+)
+
+// AssociatedData is authenticated but not encrypted alongside a sealed message.
+type AssociatedData struct {
+	Emotion string
+	NodeID  string
+	Context []byte
+}
+
+// GlyphAssociatedData binds a message to the glyph's emotion and the sending node.
+func GlyphAssociatedData(glyph LyraGlyph, nodeID string) AssociatedData {
+	return AssociatedData{Emotion: glyph.Emotion, NodeID: nodeID}
+}
+
+// Bytes returns the unambiguous encoding authenticated by the AEAD.
+func (ad AssociatedData) Bytes() []byte {
+	b := appendLengthPrefixed(nil, []byte(ad.Emotion))
+	b = appendLengthPrefixed(b, []byte(ad.NodeID))
+	return appendLengthPrefixed(b, ad.Context)
+}
+
+// NewAEAD derives a 256-bit cipher key from a QALX key and returns the selected AEAD.
+// Keys of any supported length (e.g. from QALXGenerateSecureKey) are accepted.
+func NewAEAD(alg AEADAlgorithm, key []byte) (cipher.AEAD, error) {
+	if len(key) < MinAEADKeySize {
+		return nil, ErrAEADKeyTooShort
+	}
+	if alg != AEADAES256GCM && alg != AEADChaCha20Poly1305 {
+		return nil, ErrUnknownAEADAlgorithm
+	}
+	subkey, err := hkdf.Key(sha512.New, key, nil, aeadKeyDomain+":"+string(alg), 32)
+	if err != nil {
+		return nil, err
+	}
+	if alg == AEADChaCha20Poly1305 {
+		return chacha20poly1305.New(subkey)
+	}
+	block, err := aes.NewCipher(subkey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// QALXSeal encrypts and authenticates plaintext. The output is nonce || ciphertext.
+func QALXSeal(alg AEADAlgorithm, key, plaintext []byte, ad AssociatedData) ([]byte, error) {
+	aead, err := NewAEAD(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out, plaintext, ad.Bytes()), nil
+}
+
+// QALXOpen authenticates and decrypts a message produced by QALXSeal.
+func QALXOpen(alg AEADAlgorithm, key, sealed []byte, ad AssociatedData) ([]byte, error) {
+	aead, err := NewAEAD(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ad.Bytes())
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,368 @@
+// crl.go - Signed, versioned revocation lists for QALX mesh nodes
+package coherra
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RevocationReasonCode classifies why a node was revoked.
+type RevocationReasonCode string
+
+const (
+	RevocationUnspecified     RevocationReasonCode = "unspecified"
+	RevocationKeyCompromise   RevocationReasonCode = "key-compromise"
+	RevocationSuperseded      RevocationReasonCode = "superseded"
+	RevocationCessation       RevocationReasonCode = "cessation-of-operation"
+	RevocationPolicyViolation RevocationReasonCode = "policy-violation"
+	RevocationPropagated      RevocationReasonCode = "propagated"
+)
+
+var (
+	ErrRevocationListUnsigned  = &QALXError{"Revocation list is not signed"}
+	ErrRevocationListMalformed = &QALXError{"Malformed revocation list"}
+	ErrUnknownIssuer           = &QALXError{"Revocation list issuer is not trusted"}
+	ErrRevocationListStale     = &QALXError{"Revocation list is older than one already merged"}
+)
+
+// RevokedNodeError is returned when a node appears on a revocation list.
+type RevokedNodeError struct {
+	NodeID string
+	Code   RevocationReasonCode
+	Reason string
+}
+
+// Error returns the error message for RevokedNodeError.
+func (e *RevokedNodeError) Error() string {
+	return e.Reason
+}
+
+// RevokedNode is one revocation list entry. RevokedAt is in Unix seconds.
+type RevokedNode struct {
+	NodeID    string
+	Code      RevocationReasonCode
+	Detail    string `json:",omitempty"`
+	RevokedAt int64
+}
+
+// RevocationList is a CRL-like list of revoked nodes signed by an issuer node.
+// Every Sign increments Version. A RevocationList is not safe for concurrent
+// use; MeshNetwork keeps its own copy behind the network lock.
+type RevocationList struct {
+	Issuer    string
+	Version   uint64
+	IssuedAt  int64
+	Entries   []RevokedNode
+	Signature string `json:",omitempty"`
+	// Merged is the latest version merged from each issuer. It is local
+	// bookkeeping and not covered by the signature.
+	Merged map[string]uint64 `json:",omitempty"`
+}
+
+// NewRevocationList creates an empty, unsigned list for issuer.
+func NewRevocationList(issuer string) *RevocationList {
+	return &RevocationList{Issuer: issuer}
+}
+
+// clone returns a copy of the list that shares no entries with the original.
+func (l *RevocationList) clone() *RevocationList {
+	c := *l
+	c.Entries = slices.Clone(l.Entries)
+	c.Merged = maps.Clone(l.Merged)
+	return &c
+}
+
+// Lookup returns the entry for nodeID, if the node is revoked.
+func (l *RevocationList) Lookup(nodeID string) (RevokedNode, bool) {
+	i, ok := slices.BinarySearchFunc(l.Entries, nodeID, func(e RevokedNode, id string) int { return strings.Compare(e.NodeID, id) })
+	if !ok {
+		return RevokedNode{}, false
+	}
+	return l.Entries[i], true
+}
+
+// Check returns a *RevokedNodeError if nodeID is on the list.
+func (l *RevocationList) Check(nodeID string) error {
+	entry, ok := l.Lookup(nodeID)
+	if !ok {
+		return nil
+	}
+	return &RevokedNodeError{NodeID: nodeID, Code: entry.Code, Reason: "Mesh node is revoked: " + string(entry.Code)}
+}
+
+// Revoke adds an entry, reporting whether the list changed. A node that is already
+// listed keeps its original entry. Changing the list invalidates its signature.
+func (l *RevocationList) Revoke(entry RevokedNode) bool {
+	i, ok := slices.BinarySearchFunc(l.Entries, entry.NodeID, func(e RevokedNode, id string) int { return strings.Compare(e.NodeID, id) })
+	if ok {
+		return false
+	}
+	if entry.Code == "" {
+		entry.Code = RevocationUnspecified
+	}
+	l.Entries = slices.Insert(l.Entries, i, entry)
+	l.Signature = ""
+	return true
+}
+
+// signedBytes is the canonical encoding covered by the signature.
+func (l *RevocationList) signedBytes() []byte {
+	b, _ := json.Marshal(struct {
+		Issuer   string
+		Version  uint64
+		IssuedAt int64
+		Entries  []RevokedNode
+	}{l.Issuer, l.Version, l.IssuedAt, l.Entries})
+	return b
+}
+
+// Sign increments the version, stamps the issue time and signs the list.
+// A list without an issuer adopts the signer's node ID.
+func (l *RevocationList) Sign(signer Signer, now time.Time) error {
+	if l.Issuer == "" {
+		l.Issuer = signer.NodeID()
+	}
+	if l.Issuer != signer.NodeID() {
+		return ErrSignatureNodeMismatch
+	}
+	l.Version++
+	l.IssuedAt = now.Unix()
+	sig, err := QALXSign(signer, l.signedBytes())
+	if err != nil {
+		l.Signature = ""
+		return err
+	}
+	l.Signature = sig
+	return nil
+}
+
+// Verify checks the list's signature against the issuer's verifier.
+func (l *RevocationList) Verify(verifier Verifier) error {
+	if l.Signature == "" {
+		return ErrRevocationListUnsigned
+	}
+	if verifier.NodeID() != l.Issuer {
+		return ErrSignatureNodeMismatch
+	}
+	return QALXVerify(verifier, l.signedBytes(), l.Signature)
+}
+
+// Merge verifies a peer's list and adds any entries missing from l, returning
+// how many were added. A list older than the last version merged from its
+// issuer is rejected with ErrRevocationListStale, so an old list cannot be
+// replayed. l must be re-signed before it is distributed again.
+func (l *RevocationList) Merge(peer *RevocationList, verifier Verifier) (int, error) {
+	if err := peer.Verify(verifier); err != nil {
+		return 0, err
+	}
+	if peer.Version < l.Merged[peer.Issuer] {
+		return 0, ErrRevocationListStale
+	}
+	if l.Merged == nil {
+		l.Merged = make(map[string]uint64)
+	}
+	l.Merged[peer.Issuer] = peer.Version
+	added := 0
+	for _, entry := range peer.Entries {
+		if l.Revoke(entry) {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// ParseRevocationList decodes a list produced by json.Marshal. The signature is
+// not checked; call Verify before trusting the contents.
+func ParseRevocationList(data []byte) (*RevocationList, error) {
+	var l RevocationList
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, ErrRevocationListMalformed
+	}
+	for i, e := range l.Entries {
+		if e.NodeID == "" || (i > 0 && l.Entries[i-1].NodeID >= e.NodeID) {
+			return nil, ErrRevocationListMalformed
+		}
+	}
+	return &l, nil
+}
+
+// SaveRevocationList writes the list to path atomically.
+func SaveRevocationList(path string, l *RevocationList) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
+
+// LoadRevocationList reads a list written by SaveRevocationList.
+func LoadRevocationList(path string) (*RevocationList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRevocationList(data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory, syncs it
+// and renames it over path, so readers see either the old or the new contents.
+// The directory is synced too, so the rename survives a crash.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes the directory's entries, such as a just renamed file, durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// SetRevocationList replaces the network's revocation list and revokes every
+// listed node currently in the network.
+func (net *MeshNetwork) SetRevocationList(l *RevocationList) {
+	net.mu.Lock()
+	defer net.unlock()
+	net.crl = l.clone()
+	net.crlDirty = true
+	net.enforceRevocationsLocked()
+}
+
+// RevocationList returns a copy of the network's revocation list, or nil if none is set.
+func (net *MeshNetwork) RevocationList() *RevocationList {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	if net.crl == nil {
+		return nil
+	}
+	return net.crl.clone()
+}
+
+// SignRevocationList signs the network's revocation list, creating an empty one
+// for the signer if none is set, and returns a copy ready for distribution.
+func (net *MeshNetwork) SignRevocationList(signer Signer) (*RevocationList, error) {
+	net.mu.Lock()
+	defer net.unlock()
+	if net.crl == nil {
+		net.crl = NewRevocationList(signer.NodeID())
+	}
+	if err := net.crl.Sign(signer, net.clock()); err != nil {
+		return nil, err
+	}
+	net.crlDirty = true
+	return net.crl.clone(), nil
+}
+
+// MergeRevocationList verifies a peer's list, merges it into the network's list
+// and revokes the newly listed nodes. It returns the number of entries added.
+func (net *MeshNetwork) MergeRevocationList(peer *RevocationList, verifier Verifier) (int, error) {
+	net.mu.Lock()
+	defer net.unlock()
+	// A list is only turned on once a peer's list has been verified.
+	merged := NewRevocationList("")
+	if net.crl != nil {
+		merged = net.crl.clone()
+	}
+	added, err := merged.Merge(peer, verifier)
+	if err != nil {
+		return 0, err
+	}
+	net.crl = merged
+	net.crlDirty = true
+	net.enforceRevocationsLocked()
+	return added, nil
+}
+
+// recordRevocationLocked adds a revocation to the network's list, if one is set.
+func (net *MeshNetwork) recordRevocationLocked(nodeID string, code RevocationReasonCode, detail string) {
+	if net.crl != nil && net.crl.Revoke(RevokedNode{NodeID: nodeID, Code: code, Detail: detail, RevokedAt: net.clock().Unix()}) {
+		net.crlDirty = true
+	}
+}
+
+// enforceRevocationsLocked revokes every listed node that is not revoked yet.
+func (net *MeshNetwork) enforceRevocationsLocked() {
+	if net.crl == nil {
+		return
+	}
+	for _, entry := range net.crl.Entries {
+		if node, ok := net.nodes[entry.NodeID]; ok {
+			net.revokeLocked(node, revocationListReason(entry))
+		}
+	}
+}
+
+func revocationListReason(entry RevokedNode) string {
+	if entry.Detail != "" {
+		return entry.Detail
+	}
+	return string(entry.Code)
+}
+
+// checkRevocationList returns a *RevokedNodeError if the node is on the network's list.
+func (net *MeshNetwork) checkRevocationList(nodeID string) error {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	if net.crl == nil {
+		return nil
+	}
+	return net.crl.Check(nodeID)
+}
+
+// checkRevocationLists returns the first *RevokedNodeError for nodeID among lists.
+func checkRevocationLists(nodeID string, lists []*RevocationList) error {
+	for _, l := range lists {
+		if l == nil {
+			continue
+		}
+		if err := l.Check(nodeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateNode is ValidateMeshNode with the network's revocation list. The node
+// need not be in the network.
+func (net *MeshNetwork) ValidateNode(node QuantumMeshNode, resonance float64, glyph LyraGlyph, meshScore float64) error {
+	return ValidateMeshNode(node, resonance, glyph, meshScore, net.RevocationList())
+}
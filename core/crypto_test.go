@@ -0,0 +1,285 @@
+package coherra
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// crypto_test.go - Signature, key agreement and encryption tests for QALX
+
+func testMeshNode(t testing.TB) QuantumMeshNode {
+	t.Helper()
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567890}
+	return GenerateMeshNode(InitializeQuantumMetricsWithGlyph(glyph))
+}
+
+func TestQALXSignVerify(t *testing.T) {
+	node := testMeshNode(t)
+	signer, err := NewEd25519Signer(node, nil)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer: %v", err)
+	}
+	data := []byte("quantum key material")
+	sig, err := QALXSign(signer, data)
+	if err != nil {
+		t.Fatalf("QALXSign: %v", err)
+	}
+	verifier, err := NewVerifier(SignatureEd25519, node.ID, signer.PublicKey())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	if err := QALXVerify(verifier, data, sig); err != nil {
+		t.Errorf("QALXVerify rejected a valid signature: %v", err)
+	}
+	if err := QALXVerify(verifier, []byte("tampered"), sig); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature for tampered data, got %v", err)
+	}
+	if err := QALXVerify(verifier, data, "garbage"); err != ErrMalformedSignature {
+		t.Errorf("Expected ErrMalformedSignature, got %v", err)
+	}
+}
+
+func TestQALXSignBoundToNode(t *testing.T) {
+	node := testMeshNode(t)
+	other := testMeshNode(t)
+	signer, err := NewEd25519Signer(node, nil)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer: %v", err)
+	}
+	data := []byte("payload")
+	sig, err := QALXSign(signer, data)
+	if err != nil {
+		t.Fatalf("QALXSign: %v", err)
+	}
+	// Same key, different claimed identity: must not verify.
+	impostor, err := NewEd25519Verifier(other.ID, signer.PublicKey())
+	if err != nil {
+		t.Fatalf("NewEd25519Verifier: %v", err)
+	}
+	if err := QALXVerify(impostor, data, sig); err != ErrSignatureNodeMismatch {
+		t.Errorf("Expected ErrSignatureNodeMismatch, got %v", err)
+	}
+	raw, _ := signer.Sign(data)
+	if err := impostor.Verify(data, raw); err == nil {
+		t.Error("Raw signature verified under a different node ID")
+	}
+	if !bytes.Equal(signer.Verifier().key, signer.PublicKey()) {
+		t.Error("Signer verifier does not match public key")
+	}
+}
+
+func TestKEMRoundTrip(t *testing.T) {
+	for _, scheme := range []KEMScheme{KEMMLKEM768, KEMMLKEM1024} {
+		dk, err := GenerateKEMKeyPair(scheme)
+		if err != nil {
+			t.Fatalf("%s: GenerateKEMKeyPair: %v", scheme, err)
+		}
+		ek, err := NewKEMEncapsulationKey(scheme, dk.EncapsulationKey().Bytes())
+		if err != nil {
+			t.Fatalf("%s: NewKEMEncapsulationKey: %v", scheme, err)
+		}
+		shared, ct := ek.Encapsulate()
+		got, err := dk.Decapsulate(ct)
+		if err != nil {
+			t.Fatalf("%s: Decapsulate: %v", scheme, err)
+		}
+		if !bytes.Equal(shared, got) || len(shared) != KEMSharedKeySize {
+			t.Errorf("%s: shared secrets differ", scheme)
+		}
+		restored, err := NewKEMDecapsulationKey(scheme, dk.Bytes())
+		if err != nil {
+			t.Fatalf("%s: NewKEMDecapsulationKey: %v", scheme, err)
+		}
+		if again, _ := restored.Decapsulate(ct); !bytes.Equal(shared, again) {
+			t.Errorf("%s: restored key decapsulated a different secret", scheme)
+		}
+	}
+	if _, err := GenerateKEMKeyPair("ML-KEM-512"); err != ErrUnknownKEMScheme {
+		t.Errorf("Expected ErrUnknownKEMScheme, got %v", err)
+	}
+}
+
+func TestQALXTweakSharedSecret(t *testing.T) {
+	shared := bytes.Repeat([]byte{7}, KEMSharedKeySize)
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567890}
+	a, err := QALXTweakSharedSecret(shared, glyph, 0.9)
+	if err != nil {
+		t.Fatalf("QALXTweakSharedSecret: %v", err)
+	}
+	b, _ := QALXTweakSharedSecret(shared, glyph, 0.9)
+	if !bytes.Equal(a, b) {
+		t.Error("Tweak is not deterministic")
+	}
+	glyph.Emotion = "joy"
+	c, _ := QALXTweakSharedSecret(shared, glyph, 0.9)
+	if bytes.Equal(a, c) {
+		t.Error("Tweak ignored glyph emotion")
+	}
+	d, _ := QALXTweakSharedSecret(shared, LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567890}, 0.8)
+	if bytes.Equal(a, d) {
+		t.Error("Tweak ignored mesh score")
+	}
+}
+
+func TestHybridHandshake(t *testing.T) {
+	alice, bob := testMeshNode(t), testMeshNode(t)
+	aliceSigner, _ := NewEd25519Signer(alice, nil)
+	bobSigner, _ := NewEd25519Signer(bob, nil)
+
+	initiator := NewHybridHandshake(alice, aliceSigner)
+	responder := NewHybridHandshake(bob, bobSigner)
+	hello, err := initiator.Hello()
+	if err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	resp, bobSession, err := responder.Respond(hello, alice, aliceSigner.Verifier())
+	if err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	aliceSession, err := initiator.Finish(resp, bob, bobSigner.Verifier())
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if !bytes.Equal(aliceSession.Key, bobSession.Key) || len(aliceSession.Key) != SessionKeySize {
+		t.Error("Session keys differ")
+	}
+	if aliceSession.PeerID != bob.ID || bobSession.PeerID != alice.ID {
+		t.Error("Session peers not bound to node IDs")
+	}
+}
+
+func TestHybridHandshakeRejectsWrongPeer(t *testing.T) {
+	alice, bob, mallory := testMeshNode(t), testMeshNode(t), testMeshNode(t)
+	aliceSigner, _ := NewEd25519Signer(alice, nil)
+	malSigner, _ := NewEd25519Signer(mallory, nil)
+
+	hello, err := NewHybridHandshake(alice, aliceSigner).Hello()
+	if err != nil {
+		t.Fatalf("Hello: %v", err)
+	}
+	responder := NewHybridHandshake(bob, nil)
+	if _, _, err := responder.Respond(hello, mallory, malSigner.Verifier()); err != ErrHandshakePeerMismatch {
+		t.Errorf("Expected ErrHandshakePeerMismatch, got %v", err)
+	}
+	// Claim to be Alice but sign with Mallory's key.
+	forged, _ := NewHybridHandshake(alice, malSigner).Hello()
+	if _, _, err := responder.Respond(forged, alice, aliceSigner.Verifier()); err == nil {
+		t.Error("Responder accepted a hello signed by the wrong node")
+	}
+	unsigned, _ := NewHybridHandshake(alice, nil).Hello()
+	if _, _, err := responder.Respond(unsigned, alice, aliceSigner.Verifier()); err != ErrHandshakeUnsigned {
+		t.Errorf("Expected ErrHandshakeUnsigned, got %v", err)
+	}
+}
+
+func TestQALXGenerateSecureKeyReproducible(t *testing.T) {
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567890}
+	metrics := InitializeQuantumMetricsWithGlyph(glyph)
+	seed := bytes.Repeat([]byte{0x42}, 64)
+	a, err := QALXGenerateSecureKeyFrom(bytes.NewReader(seed), metrics, glyph, 1.0)
+	if err != nil {
+		t.Fatalf("QALXGenerateSecureKeyFrom: %v", err)
+	}
+	b, _ := QALXGenerateSecureKeyFrom(bytes.NewReader(seed), metrics, glyph, 1.0)
+	if !bytes.Equal(a, b) {
+		t.Error("Same secret and context produced different keys")
+	}
+	glyph.Intensity = 0.9
+	c, _ := QALXGenerateSecureKeyFrom(bytes.NewReader(seed), metrics, glyph, 1.0)
+	if bytes.Equal(a, c) {
+		t.Error("Key not bound to glyph context")
+	}
+	if _, err := QALXGenerateSecureKeyFrom(bytes.NewReader(seed[:10]), metrics, glyph, 1.0); err == nil {
+		t.Error("Expected error for short randomness source")
+	}
+	d, _ := QALXGenerateSecureKey(metrics, glyph, 1.0)
+	e, _ := QALXGenerateSecureKey(metrics, glyph, 1.0)
+	if bytes.Equal(d, e) {
+		t.Error("CSPRNG-backed keys repeated")
+	}
+}
+
+func TestKeyLengthHonorsMetrics(t *testing.T) {
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567890}
+	standard, err := QALXGenerateEncryptionKey(InitializeEncryptionMetricsWithGlyph(glyph), glyph, 1.0)
+	if err != nil {
+		t.Fatalf("standard profile: %v", err)
+	}
+	high, err := QALXGenerateEncryptionKey(InitializeHighSecurityEncryptionMetricsWithGlyph(glyph), glyph, 1.0)
+	if err != nil {
+		t.Fatalf("high-security profile: %v", err)
+	}
+	if len(standard) != 32 || len(high) != 4096 {
+		t.Errorf("Key lengths: standard=%d high=%d, want 32 and 4096", len(standard), len(high))
+	}
+
+	// Default metrics keep producing 64-byte keys, as do metrics with no length set.
+	metrics := InitializeQuantumMetricsWithGlyph(glyph)
+	if key, err := QALXGenerateSecureKey(metrics, glyph, 1.0); err != nil || len(key) != 64 {
+		t.Errorf("Default metrics key length: got %d (%v), want 64", len(key), err)
+	}
+	metrics.KeyLength, metrics.KeyStrength = 0, 0
+	if key, _ := QALXGenerateSecureKey(metrics, glyph, 1.0); len(key) != DefaultKeyLength {
+		t.Errorf("Default key length: got %d, want %d", len(key), DefaultKeyLength)
+	}
+	lengths := SupportedKeyLengths()
+	lengths[0] = 33
+	if slices.Contains(SupportedKeyLengths(), 33) {
+		t.Error("SupportedKeyLengths exposes the validation table")
+	}
+	cases := []struct{ length, strength int }{
+		{33, 256}, // unsupported length
+		{32, 512}, // unsupported strength
+		{16, 256}, // too short for strength
+		{-1, 128}, // negative length
+		{4097, 0}, // beyond supported sizes
+	}
+	for _, tc := range cases {
+		metrics.KeyLength, metrics.KeyStrength = tc.length, tc.strength
+		_, err := QALXGenerateSecureKey(metrics, glyph, 1.0)
+		specErr, ok := err.(*KeySpecError)
+		if !ok {
+			t.Errorf("length=%d strength=%d: expected *KeySpecError, got %v", tc.length, tc.strength, err)
+			continue
+		}
+		if specErr.KeyLength != tc.length {
+			t.Errorf("KeySpecError.KeyLength = %d, want %d", specErr.KeyLength, tc.length)
+		}
+	}
+}
+
+func TestQALXSealOpen(t *testing.T) {
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567890}
+	node := testMeshNode(t)
+	key, err := QALXGenerateSecureKey(node.Metrics, glyph, 1.0)
+	if err != nil {
+		t.Fatalf("QALXGenerateSecureKey: %v", err)
+	}
+	ad := GlyphAssociatedData(glyph, node.ID)
+	plaintext := []byte("mesh telemetry")
+	for _, alg := range []AEADAlgorithm{AEADAES256GCM, AEADChaCha20Poly1305} {
+		sealed, err := QALXSeal(alg, key, plaintext, ad)
+		if err != nil {
+			t.Fatalf("%s: QALXSeal: %v", alg, err)
+		}
+		got, err := QALXOpen(alg, key, sealed, ad)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%s: round trip failed: %v", alg, err)
+		}
+		wrongAD := GlyphAssociatedData(LyraGlyph{Emotion: "fear"}, node.ID)
+		if _, err := QALXOpen(alg, key, sealed, wrongAD); err != ErrDecryptionFailed {
+			t.Errorf("%s: expected ErrDecryptionFailed for mismatched emotion, got %v", alg, err)
+		}
+		sealed[len(sealed)-1] ^= 1
+		if _, err := QALXOpen(alg, key, sealed, ad); err != ErrDecryptionFailed {
+			t.Errorf("%s: expected ErrDecryptionFailed for tampered ciphertext, got %v", alg, err)
+		}
+	}
+	if _, err := QALXSeal(AEADAES256GCM, key[:16], plaintext, ad); err != ErrAEADKeyTooShort {
+		t.Errorf("Expected ErrAEADKeyTooShort, got %v", err)
+	}
+	if _, err := QALXSeal("rot13", key, plaintext, ad); err != ErrUnknownAEADAlgorithm {
+		t.Errorf("Expected ErrUnknownAEADAlgorithm, got %v", err)
+	}
+}
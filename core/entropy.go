@@ -2,13 +2,16 @@
 package coherra
 
 import (
-	"crypto/rand"
+	"crypto/hkdf"
 	"crypto/sha512"
-	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
 	"math"
+	"slices"
 )
 
-// EntropyPoolSize is the size of the entropy pool for quantum key generation.
+// EntropyPoolSize is the number of source bytes drawn and health-tested per pool reseed.
 const EntropyPoolSize = 1024
 
 // Normalization bounds and thresholds
@@ -21,15 +24,63 @@ const (
 	QREDefaultCompositeThreshold = 0.5
 )
 
-var entropyPool []byte = initializeEntropyPool()
+// Key derivation parameters for QALXGenerateSecureKey.
+const (
+	quantumKeyDomain    = "QALX-KDF-v1"
+	encryptionKeyDomain = "QALX-KDF-ENC-v1"
+	// DefaultKeyLength is used when metrics leave KeyLength unset (bytes).
+	DefaultKeyLength = 64
+	// DefaultKeyStrength is used when metrics leave KeyStrength unset (bits).
+	DefaultKeyStrength = 256
+)
 
-func initializeEntropyPool() []byte {
-	pool := make([]byte, EntropyPoolSize)
-	_, err := rand.Read(pool)
-	if err != nil {
-		panic("Failed to initialize entropy pool")
+var (
+	supportedKeyLengths   = []int{16, 24, 32, 48, 64, 128, 256, 512, 1024, 2048, 4096}
+	supportedKeyStrengths = []int{128, 192, 256}
+)
+
+// SupportedKeyLengths returns the key lengths, in bytes, accepted by key generation.
+func SupportedKeyLengths() []int {
+	return slices.Clone(supportedKeyLengths)
+}
+
+// SupportedKeyStrengths returns the security strengths, in bits, accepted by key generation.
+func SupportedKeyStrengths() []int {
+	return slices.Clone(supportedKeyStrengths)
+}
+
+// KeySpecError reports an unsupported KeyLength/KeyStrength combination.
+type KeySpecError struct {
+	KeyLength   int
+	KeyStrength int
+	Reason      string
+}
+
+// Error returns the error message for KeySpecError.
+func (e *KeySpecError) Error() string {
+	return e.Reason
+}
+
+// resolveKeySpec applies defaults and validates a KeyLength (bytes) and KeyStrength (bits) pair.
+// It returns the output length and the size of the CSPRNG secret to draw.
+func resolveKeySpec(keyLength, keyStrength int) (length int, secretSize int, err error) {
+	if keyLength == 0 {
+		keyLength = DefaultKeyLength
+	}
+	if keyStrength == 0 {
+		keyStrength = DefaultKeyStrength
 	}
-	return pool
+	if !slices.Contains(supportedKeyLengths, keyLength) {
+		return 0, 0, &KeySpecError{keyLength, keyStrength, fmt.Sprintf("Unsupported key length %d bytes", keyLength)}
+	}
+	if !slices.Contains(supportedKeyStrengths, keyStrength) {
+		return 0, 0, &KeySpecError{keyLength, keyStrength, fmt.Sprintf("Unsupported key strength %d bits", keyStrength)}
+	}
+	if keyLength*8 < keyStrength {
+		return 0, 0, &KeySpecError{keyLength, keyStrength, fmt.Sprintf("Key length %d bytes cannot carry %d bits of strength", keyLength, keyStrength)}
+	}
+	// Draw twice the target strength so HKDF input is never the bottleneck.
+	return keyLength, keyStrength / 4, nil
 }
 
 // Normalization helper for QRE
@@ -48,13 +99,56 @@ func ComputeQRE(metrics QuantumMetrics, glyph LyraGlyph, meshScore float64, reso
 	return math.Log2(sum + 1)
 }
 
+// QALXGenerateSecureKey derives a key from the default entropy pool bound to the metrics and glyph.
+// The key is metrics.KeyLength bytes long at metrics.KeyStrength bits of strength;
+// unsupported combinations return a *KeySpecError.
 func QALXGenerateSecureKey(metrics QuantumMetrics, glyph LyraGlyph, meshScore float64) ([]byte, error) {
+	return QALXGenerateSecureKeyFrom(defaultEntropyPool, metrics, glyph, meshScore)
+}
+
+// QALXGenerateSecureKeyFrom is QALXGenerateSecureKey with an explicit randomness source.
+//
+// The KDF pipeline is:
+//  1. read a KeyStrength/4-byte secret from random (the health-tested default pool in production),
+//  2. encode the metrics, glyph and mesh score into a context label,
+//  3. expand to KeyLength bytes with HKDF-SHA-512(secret, salt=nil, info=label).
+//
+// The metrics and glyph are public, so they only bind the key to its context;
+// all secrecy comes from random. Injecting a fixed reader makes output reproducible.
+func QALXGenerateSecureKeyFrom(random io.Reader, metrics QuantumMetrics, glyph LyraGlyph, meshScore float64) ([]byte, error) {
+	if err := glyph.Validate(); err != nil {
+		return nil, err
+	}
 	if metrics.Coherence < MinCoherence {
 		return nil, ErrInsufficientCoherence
 	}
-	quantumEntropy := harvestQuantumEntropy(metrics)
-	mixedEntropy := mixEntropy(quantumEntropy, glyph, meshScore)
-	return amplifyQuantumKey(mixedEntropy, metrics, glyph), nil
+	return generateKey(random, metrics.KeyLength, metrics.KeyStrength, quantumKeyInfo(metrics, glyph, meshScore))
+}
+
+// QALXGenerateEncryptionKey derives a key sized by an EncryptionMetrics profile,
+// so InitializeHighSecurityEncryptionMetricsWithGlyph yields longer material.
+func QALXGenerateEncryptionKey(metrics EncryptionMetrics, glyph LyraGlyph, meshScore float64) ([]byte, error) {
+	return QALXGenerateEncryptionKeyFrom(defaultEntropyPool, metrics, glyph, meshScore)
+}
+
+// QALXGenerateEncryptionKeyFrom is QALXGenerateEncryptionKey with an explicit randomness source.
+func QALXGenerateEncryptionKeyFrom(random io.Reader, metrics EncryptionMetrics, glyph LyraGlyph, meshScore float64) ([]byte, error) {
+	if err := glyph.Validate(); err != nil {
+		return nil, err
+	}
+	return generateKey(random, metrics.KeyLength, metrics.KeyStrength, encryptionKeyInfo(metrics, glyph, meshScore))
+}
+
+func generateKey(random io.Reader, keyLength, keyStrength int, info []byte) ([]byte, error) {
+	length, secretSize, err := resolveKeySpec(keyLength, keyStrength)
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, secretSize)
+	if _, err := io.ReadFull(random, secret); err != nil {
+		return nil, err
+	}
+	return deriveQuantumKey(secret, info, length)
 }
 
 var ErrInsufficientCoherence = &QALXError{"Insufficient quantum coherence for secure key generation"}
@@ -67,67 +161,43 @@ func (e *QALXError) Error() string {
 	return e.msg
 }
 
-func harvestQuantumEntropy(metrics QuantumMetrics) []byte {
-	entropyBuffer := make([]byte, 32)
-	quantumFactors := append([]float64{metrics.Coherence, metrics.Phase, metrics.Amplitude}, metrics.Harmonics...)
-	for i := 0; i < len(entropyBuffer); i++ {
-		quantum := math.Sin(quantumFactors[i%len(quantumFactors)] * math.Pi)
-		entropyBuffer[i] = byte((quantum + 1) * 128)
-	}
-	return entropyBuffer
+// deriveQuantumKey expands secret into a key of the requested length.
+func deriveQuantumKey(secret []byte, info []byte, length int) ([]byte, error) {
+	return hkdf.Key(sha512.New, secret, nil, string(info), length)
 }
 
-func mixEntropy(quantumEntropy []byte, glyph LyraGlyph, meshScore float64) []byte {
-	h := sha512.New()
-	h.Write(entropyPool)
-	h.Write(quantumEntropy)
-	bias := byte((glyph.Intensity * glyph.EthicsScore * meshScore) * 128)
-	for i := range quantumEntropy {
-		quantumEntropy[i] ^= bias
+// quantumKeyInfo encodes the public key-generation context as the HKDF info label.
+func quantumKeyInfo(metrics QuantumMetrics, glyph LyraGlyph, meshScore float64) []byte {
+	info := append([]byte(quantumKeyDomain), 0)
+	for _, v := range []float64{metrics.Coherence, metrics.Phase, metrics.Amplitude, metrics.PhaseShift, metrics.EntropyScore, metrics.EntropyQuality, meshScore} {
+		info = binary.BigEndian.AppendUint64(info, math.Float64bits(v))
 	}
-	h.Write(quantumEntropy)
-	entropyPool = h.Sum(nil)
-	return entropyPool
-}
-
-func amplifyQuantumKey(entropy []byte, metrics QuantumMetrics, glyph LyraGlyph) []byte {
-	keyBuffer := make([]byte, 64)
-	glyphPhase := math.Sin(glyph.Intensity * glyph.EthicsScore * math.Pi)
-	for i := 0; i < len(keyBuffer); i++ {
-		harmonicFactor := metrics.Harmonics[i%len(metrics.Harmonics)]
-		quantumPhase := math.Sin(metrics.Phase * harmonicFactor * glyphPhase)
-		keyBuffer[i] = entropy[i%len(entropy)] ^ byte((quantumPhase+1)*128)
+	info = binary.BigEndian.AppendUint32(info, uint32(len(metrics.Harmonics)))
+	for _, h := range metrics.Harmonics {
+		info = binary.BigEndian.AppendUint64(info, math.Float64bits(h))
 	}
-	return keyBuffer
+	info = appendLengthPrefixed(info, []byte(metrics.Pattern))
+	info = binary.BigEndian.AppendUint32(info, uint32(metrics.KeyLength))
+	info = binary.BigEndian.AppendUint32(info, uint32(metrics.KeyStrength))
+	return appendGlyphContext(info, glyph)
 }
 
-// QRE-based validation: composite and QRE threshold
-func QALXValidateQuantumSecurity(metrics QuantumMetrics, resonance float64, glyph LyraGlyph, meshScore float64) bool {
-	threshold := QREDefaultThreshold
-	compositeThreshold := QREDefaultCompositeThreshold
-	volatilityBonus := 0.0
-	driftComp := 1.0
-	if glyph.Emotion == "trust" {
-		if glyph.Intensity < 0.90 && metrics.Coherence < 0.90 {
-			threshold = QRETrustThreshold
-			compositeThreshold = QRETrustCompositeThreshold
-			glyph.EthicsScore += 0.02 * meshScore
-			driftComp = 1.0 + 0.01*math.Abs(float64(glyph.Timestamp%1000))/1000
-			volatilityBonus = 0.02 * math.Abs(math.Sin(float64(glyph.Timestamp%360)*math.Pi/180))
-		} else {
-			threshold = 0.4
-			compositeThreshold = 0.45
-			glyph.EthicsScore += 0.01 * meshScore
-		}
+// encryptionKeyInfo encodes an EncryptionMetrics profile as the HKDF info label.
+func encryptionKeyInfo(metrics EncryptionMetrics, glyph LyraGlyph, meshScore float64) []byte {
+	info := append([]byte(encryptionKeyDomain), 0)
+	for _, v := range []float64{metrics.EntropyQuality, metrics.QuantumResistance, metrics.CoherenceThreshold, metrics.EntropyScore, metrics.PhaseShift, metrics.Strength, meshScore} {
+		info = binary.BigEndian.AppendUint64(info, math.Float64bits(v))
 	}
-	if resonance < threshold {
-		return false
+	info = binary.BigEndian.AppendUint32(info, uint32(len(metrics.Harmonics)))
+	for _, h := range metrics.Harmonics {
+		info = binary.BigEndian.AppendUint64(info, math.Float64bits(h))
 	}
-	composite := metrics.Coherence*resonance*glyph.EthicsScore*meshScore*driftComp + volatilityBonus
-	qre := ComputeQRE(metrics, glyph, meshScore, resonance)
-	return composite > compositeThreshold && qre > 2.0
+	info = binary.BigEndian.AppendUint32(info, uint32(metrics.KeyLength))
+	info = binary.BigEndian.AppendUint32(info, uint32(metrics.KeyStrength))
+	return appendGlyphContext(info, glyph)
 }
 
-func QALXSign(data []byte) string {
-	return base64.StdEncoding.EncodeToString(data)
+// QRE-based validation: composite and QRE threshold
+func QALXValidateQuantumSecurity(metrics QuantumMetrics, resonance float64, glyph LyraGlyph, meshScore float64) bool {
+	return DefaultGlyphRegistry().ValidateQuantumSecurity(metrics, resonance, glyph, meshScore)
 }
@@ -0,0 +1,270 @@
+// entropy_pool.go - Concurrency-safe entropy pool with reseeding and health tests
+package coherra
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Reseed policy for EntropyPool.
+const (
+	DefaultReseedInterval = time.Minute
+	DefaultReseedBytes    = 1 << 20
+)
+
+// Health test parameters (NIST SP 800-90B section 4.4). Source bytes are assumed
+// to carry at least healthMinEntropy bits each; alpha is the false-positive rate.
+const (
+	healthMinEntropy = 4
+	healthAlphaLog2  = 40
+	aptWindowSize    = 512
+)
+
+// EntropyHealthError reports a failed continuous health test. A pool that
+// returns it stays failed: it never falls back to unchecked input.
+type EntropyHealthError struct {
+	Test   string
+	Reason string
+}
+
+// Error returns the error message for EntropyHealthError.
+func (e *EntropyHealthError) Error() string {
+	return e.Reason
+}
+
+// healthTester runs the repetition count and adaptive proportion tests over a byte stream.
+type healthTester struct {
+	rctCutoff  int
+	aptCutoff  int
+	last       byte
+	runLength  int
+	aptRef     byte
+	aptCount   int
+	aptSamples int
+}
+
+func newHealthTester() *healthTester {
+	return &healthTester{
+		rctCutoff: 1 + int(math.Ceil(healthAlphaLog2/healthMinEntropy)),
+		aptCutoff: aptCutoff(aptWindowSize, math.Pow(2, -healthMinEntropy), math.Pow(2, -healthAlphaLog2)),
+	}
+}
+
+// aptCutoff returns the smallest count c with P(Binomial(window, p) >= c) <= alpha.
+func aptCutoff(window int, p, alpha float64) int {
+	tail := 0.0
+	for c := window; c >= 1; c-- {
+		lg1, _ := math.Lgamma(float64(window + 1))
+		lg2, _ := math.Lgamma(float64(c + 1))
+		lg3, _ := math.Lgamma(float64(window - c + 1))
+		tail += math.Exp(lg1 - lg2 - lg3 + float64(c)*math.Log(p) + float64(window-c)*math.Log1p(-p))
+		if tail > alpha {
+			return c + 1
+		}
+	}
+	return 1
+}
+
+// check feeds samples through both tests and returns the first failure.
+func (h *healthTester) check(samples []byte) error {
+	for _, s := range samples {
+		if h.runLength > 0 && s == h.last {
+			h.runLength++
+			if h.runLength >= h.rctCutoff {
+				return &EntropyHealthError{Test: "repetition-count", Reason: fmt.Sprintf("Entropy source repeated a value %d times", h.runLength)}
+			}
+		} else {
+			h.last, h.runLength = s, 1
+		}
+
+		if h.aptSamples == 0 {
+			h.aptRef, h.aptCount = s, 1
+		} else if s == h.aptRef {
+			h.aptCount++
+			if h.aptCount >= h.aptCutoff {
+				return &EntropyHealthError{Test: "adaptive-proportion", Reason: fmt.Sprintf("Entropy source value occurred %d times in %d samples", h.aptCount, aptWindowSize)}
+			}
+		}
+		h.aptSamples++
+		if h.aptSamples == aptWindowSize {
+			h.aptSamples = 0
+		}
+	}
+	return nil
+}
+
+// EntropyPool is a health-tested, periodically reseeded generator safe for concurrent use.
+// Output is SHA-512 in counter mode over a secret state that is ratcheted after every read.
+type EntropyPool struct {
+	mu             sync.Mutex
+	sources        []*poolSource
+	state          [sha512.Size]byte
+	counter        uint64
+	seeded         bool
+	lastReseed     time.Time
+	sinceReseed    int
+	reseedInterval time.Duration
+	reseedBytes    int
+	now            func() time.Time
+	err            error
+}
+
+// poolSource tracks one registered source with its own health tests and accounting.
+type poolSource struct {
+	src    EntropySource
+	bytes  int
+	health *healthTester
+	stats  SourceStats
+}
+
+// SourceStats reports how much a source has contributed to the pool.
+type SourceStats struct {
+	Name      string
+	BytesRead uint64
+	Reseeds   uint64
+	Failures  uint64
+	LastError error
+}
+
+// NewEntropyPool creates a pool drawing from source, or crypto/rand when source is nil.
+// Further sources can be added with RegisterSource. The pool seeds itself on first use.
+func NewEntropyPool(source io.Reader) *EntropyPool {
+	if source == nil {
+		source = OSEntropySource{}
+	}
+	primary, ok := source.(EntropySource)
+	if !ok {
+		primary = &readerSource{name: "primary", r: source}
+	}
+	return &EntropyPool{
+		sources:        []*poolSource{newPoolSource(primary, EntropyPoolSize)},
+		reseedInterval: DefaultReseedInterval,
+		reseedBytes:    DefaultReseedBytes,
+		now:            time.Now,
+	}
+}
+
+var defaultEntropyPool = NewEntropyPool(nil)
+
+// DefaultEntropyPool returns the pool used by QALXGenerateSecureKey.
+func DefaultEntropyPool() *EntropyPool {
+	return defaultEntropyPool
+}
+
+// SetReseedPolicy changes how often the pool reseeds, by elapsed time and output volume.
+func (p *EntropyPool) SetReseedPolicy(interval time.Duration, bytes int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reseedInterval, p.reseedBytes = interval, bytes
+}
+
+// Err returns the sticky health failure, if any.
+func (p *EntropyPool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func newPoolSource(src EntropySource, bytes int) *poolSource {
+	return &poolSource{src: src, bytes: bytes, health: newHealthTester(), stats: SourceStats{Name: src.Name()}}
+}
+
+// RegisterSource adds a source that contributes bytesPerReseed health-tested bytes
+// to every reseed. Each source is tested independently; any failure fails the pool.
+func (p *EntropyPool) RegisterSource(src EntropySource, bytesPerReseed int) {
+	if bytesPerReseed <= 0 {
+		bytesPerReseed = EntropyPoolSize
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sources = append(p.sources, newPoolSource(src, bytesPerReseed))
+}
+
+// SourceStats returns per-source accounting in registration order.
+func (p *EntropyPool) SourceStats() []SourceStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]SourceStats, len(p.sources))
+	for i, ps := range p.sources {
+		stats[i] = ps.stats
+	}
+	return stats
+}
+
+// Reseed mixes fresh, health-tested bytes from every registered source into the pool state.
+func (p *EntropyPool) Reseed() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reseedLocked()
+}
+
+func (p *EntropyPool) reseedLocked() error {
+	if p.err != nil {
+		return p.err
+	}
+	h := sha512.New()
+	h.Write([]byte("QALX-POOL-reseed"))
+	h.Write(p.state[:])
+	for _, ps := range p.sources {
+		seed := make([]byte, ps.bytes)
+		n, err := io.ReadFull(ps.src, seed)
+		ps.stats.BytesRead += uint64(n)
+		if err == nil {
+			err = ps.health.check(seed)
+			if _, ok := err.(*EntropyHealthError); ok {
+				p.err = err
+			}
+		}
+		if err != nil {
+			ps.stats.Failures++
+			ps.stats.LastError = err
+			return err
+		}
+		h.Write(appendLengthPrefixed(nil, []byte(ps.stats.Name)))
+		h.Write(appendLengthPrefixed(nil, seed))
+	}
+	for _, ps := range p.sources {
+		ps.stats.Reseeds++
+	}
+	h.Sum(p.state[:0])
+	p.seeded = true
+	p.lastReseed = p.now()
+	p.sinceReseed = 0
+	return nil
+}
+
+// Read fills b with pool output, reseeding first when the policy requires it.
+func (p *EntropyPool) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return 0, p.err
+	}
+	if !p.seeded || p.sinceReseed+len(b) > p.reseedBytes || p.now().Sub(p.lastReseed) >= p.reseedInterval {
+		if err := p.reseedLocked(); err != nil {
+			return 0, err
+		}
+	}
+	var block [sha512.Size]byte
+	for n := 0; n < len(b); n += sha512.Size {
+		h := sha512.New()
+		h.Write(p.state[:])
+		h.Write(binary.BigEndian.AppendUint64(nil, p.counter))
+		h.Sum(block[:0])
+		p.counter++
+		copy(b[n:], block[:])
+	}
+	// Ratchet the state so earlier output cannot be recomputed after a compromise.
+	h := sha512.New()
+	h.Write([]byte("QALX-POOL-ratchet"))
+	h.Write(p.state[:])
+	h.Write(binary.BigEndian.AppendUint64(nil, p.counter))
+	h.Sum(p.state[:0])
+	p.sinceReseed += len(b)
+	return len(b), nil
+}
@@ -0,0 +1,167 @@
+// entropy_source.go - Pluggable entropy sources for the QALX entropy pool
+package coherra
+
+import (
+	"crypto/rand"
+	"io"
+	mrand "math/rand/v2"
+	"os"
+	"sync"
+)
+
+// EntropySource is a named byte source that an EntropyPool can mix.
+// Implementations must be safe for use by one pool at a time.
+type EntropySource interface {
+	Name() string
+	io.Reader
+}
+
+// ErrEntropySourceExhausted is returned when a finite source runs out of bytes.
+var ErrEntropySourceExhausted = &QALXError{"Entropy source exhausted"}
+
+// OSEntropySource reads from the operating system CSPRNG.
+type OSEntropySource struct{}
+
+// Name returns "os".
+func (OSEntropySource) Name() string { return "os" }
+
+// Read fills p from crypto/rand.
+func (OSEntropySource) Read(p []byte) (int, error) { return rand.Read(p) }
+
+// readerSource adapts a plain io.Reader passed to NewEntropyPool.
+type readerSource struct {
+	name string
+	r    io.Reader
+}
+
+func (s *readerSource) Name() string               { return s.name }
+func (s *readerSource) Read(p []byte) (int, error) { return s.r.Read(p) }
+
+// FileEntropySource reads from a file or device such as /dev/hwrng or a recorded RNG dump.
+// The file is opened on first read; reaching the end of a regular file is reported as
+// ErrEntropySourceExhausted rather than wrapping around.
+type FileEntropySource struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// NewFileEntropySource creates a source for the given path.
+func NewFileEntropySource(path string) *FileEntropySource {
+	return &FileEntropySource{path: path}
+}
+
+// Name returns the file path.
+func (s *FileEntropySource) Name() string { return "file:" + s.path }
+
+// Read reads from the file, opening it if necessary.
+func (s *FileEntropySource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		f, err := os.Open(s.path)
+		if err != nil {
+			return 0, err
+		}
+		s.f = f
+	}
+	n, err := s.f.Read(p)
+	if err == io.EOF {
+		return n, ErrEntropySourceExhausted
+	}
+	return n, err
+}
+
+// Close releases the underlying file.
+func (s *FileEntropySource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// DeterministicEntropySource produces a reproducible ChaCha8 stream from a seed.
+// It is intended for tests only and must never back production keys.
+type DeterministicEntropySource struct {
+	mu  sync.Mutex
+	rng *mrand.ChaCha8
+}
+
+// NewDeterministicEntropySource creates a seeded test source.
+func NewDeterministicEntropySource(seed [32]byte) *DeterministicEntropySource {
+	return &DeterministicEntropySource{rng: mrand.NewChaCha8(seed)}
+}
+
+// Name returns "deterministic".
+func (s *DeterministicEntropySource) Name() string { return "deterministic" }
+
+// Read fills p from the seeded stream.
+func (s *DeterministicEntropySource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.Read(p)
+}
+
+// RecordingEntropySource passes another source through while keeping a copy of
+// every byte read, so a run can later be replayed with ReplayEntropySource.
+type RecordingEntropySource struct {
+	mu        sync.Mutex
+	src       EntropySource
+	recording []byte
+}
+
+// NewRecordingEntropySource wraps src.
+func NewRecordingEntropySource(src EntropySource) *RecordingEntropySource {
+	return &RecordingEntropySource{src: src}
+}
+
+// Name returns the wrapped source name.
+func (s *RecordingEntropySource) Name() string { return s.src.Name() }
+
+// Read reads from the wrapped source and records the bytes.
+func (s *RecordingEntropySource) Read(p []byte) (int, error) {
+	n, err := s.src.Read(p)
+	s.mu.Lock()
+	s.recording = append(s.recording, p[:n]...)
+	s.mu.Unlock()
+	return n, err
+}
+
+// Recording returns a copy of the bytes read so far.
+func (s *RecordingEntropySource) Recording() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.recording...)
+}
+
+// ReplayEntropySource returns previously recorded bytes in order.
+type ReplayEntropySource struct {
+	mu   sync.Mutex
+	name string
+	data []byte
+	off  int
+}
+
+// NewReplayEntropySource replays recording under the given source name.
+func NewReplayEntropySource(name string, recording []byte) *ReplayEntropySource {
+	return &ReplayEntropySource{name: name, data: append([]byte(nil), recording...)}
+}
+
+// Name returns the replayed source name.
+func (s *ReplayEntropySource) Name() string { return s.name }
+
+// Read returns the next recorded bytes, or ErrEntropySourceExhausted at the end.
+func (s *ReplayEntropySource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.off >= len(s.data) {
+		return 0, ErrEntropySourceExhausted
+	}
+	n := copy(p, s.data[s.off:])
+	s.off += n
+	return n, nil
+}
@@ -0,0 +1,174 @@
+package coherra
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// entropy_test.go - Entropy pool concurrency, reseeding and health test coverage
+
+type constantReader byte
+
+func (c constantReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(c)
+	}
+	return len(p), nil
+}
+
+func TestEntropyPoolConcurrentKeyGeneration(t *testing.T) {
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567890}
+	metrics := InitializeQuantumMetricsWithGlyph(glyph)
+	metrics.KeyLength = 32
+	var wg sync.WaitGroup
+	keys := make([][]byte, 32)
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key, err := QALXGenerateSecureKey(metrics, glyph, 1.0)
+			if err != nil {
+				t.Errorf("QALXGenerateSecureKey: %v", err)
+			}
+			keys[i] = key
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if seen[string(k)] {
+			t.Fatal("Concurrent key generation produced a duplicate key")
+		}
+		seen[string(k)] = true
+	}
+}
+
+func TestEntropyPoolFailsClosed(t *testing.T) {
+	pool := NewEntropyPool(constantReader(0xAA))
+	buf := make([]byte, 32)
+	_, err := pool.Read(buf)
+	healthErr, ok := err.(*EntropyHealthError)
+	if !ok || healthErr.Test != "repetition-count" {
+		t.Fatalf("Expected repetition-count failure, got %v", err)
+	}
+	if _, err := pool.Read(buf); err != healthErr {
+		t.Errorf("Pool did not stay failed: %v", err)
+	}
+	if !bytes.Equal(buf, make([]byte, 32)) {
+		t.Error("Failed pool wrote output")
+	}
+}
+
+func TestHealthTesterAdaptiveProportion(t *testing.T) {
+	h := newHealthTester()
+	// Alternate a dominant value with distinct fillers: no long runs, but a biased proportion.
+	samples := make([]byte, aptWindowSize)
+	for i := range samples {
+		if i%2 == 0 {
+			samples[i] = 0x55
+		} else {
+			samples[i] = byte(i)
+		}
+	}
+	err := h.check(samples)
+	if healthErr, ok := err.(*EntropyHealthError); !ok || healthErr.Test != "adaptive-proportion" {
+		t.Errorf("Expected adaptive-proportion failure, got %v", err)
+	}
+	if h.rctCutoff != 11 || h.aptCutoff <= 32 || h.aptCutoff >= aptWindowSize/2 {
+		t.Errorf("Unexpected cutoffs: rct=%d apt=%d", h.rctCutoff, h.aptCutoff)
+	}
+}
+
+func TestEntropyPoolReseedsOnInterval(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	src := &countingReader{}
+	pool := NewEntropyPool(src)
+	pool.now = func() time.Time { return now }
+	buf := make([]byte, 16)
+	pool.Read(buf)
+	pool.Read(buf)
+	if src.reads != 1 {
+		t.Fatalf("Expected a single seeding read, got %d", src.reads)
+	}
+	now = now.Add(DefaultReseedInterval)
+	pool.Read(buf)
+	if src.reads != 2 {
+		t.Errorf("Pool did not reseed after interval: %d reads", src.reads)
+	}
+}
+
+type countingReader struct {
+	reads int
+	n     byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	for i := range p {
+		c.n = c.n*31 + 17
+		p[i] = c.n ^ byte(i>>3)
+	}
+	return len(p), nil
+}
+
+func TestEntropySourcesRecordReplay(t *testing.T) {
+	var seed [32]byte
+	seed[0] = 1
+	rec := NewRecordingEntropySource(NewDeterministicEntropySource(seed))
+	pool := NewEntropyPool(rec)
+	a := make([]byte, 48)
+	if _, err := pool.Read(a); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	replay := NewEntropyPool(NewReplayEntropySource(rec.Name(), rec.Recording()))
+	b := make([]byte, 48)
+	if _, err := replay.Read(b); err != nil {
+		t.Fatalf("Replay read: %v", err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Error("Replayed pool output differs from the recorded run")
+	}
+	if err := replay.Reseed(); err != ErrEntropySourceExhausted {
+		t.Errorf("Expected ErrEntropySourceExhausted, got %v", err)
+	}
+	if stats := replay.SourceStats(); stats[0].Failures != 1 || stats[0].Reseeds != 1 {
+		t.Errorf("Unexpected replay stats: %+v", stats[0])
+	}
+}
+
+func TestEntropyPoolRegisteredSources(t *testing.T) {
+	var seed [32]byte
+	path := t.TempDir() + "/qrng.dump"
+	dump := make([]byte, 256)
+	NewDeterministicEntropySource(seed).Read(dump)
+	if err := os.WriteFile(path, dump, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file := NewFileEntropySource(path)
+	defer file.Close()
+
+	pool := NewEntropyPool(nil)
+	pool.RegisterSource(file, 128)
+	buf := make([]byte, 32)
+	if _, err := pool.Read(buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	stats := pool.SourceStats()
+	if len(stats) != 2 || stats[0].Name != "os" || stats[1].BytesRead != 128 || stats[1].Reseeds != 1 {
+		t.Fatalf("Unexpected source stats: %+v", stats)
+	}
+	pool.Reseed()
+	if err := pool.Reseed(); err != ErrEntropySourceExhausted {
+		t.Errorf("Expected exhausted file source, got %v", err)
+	}
+
+	stuck := NewEntropyPool(nil)
+	stuck.RegisterSource(NewReplayEntropySource("stuck", make([]byte, 64)), 64)
+	if _, err := stuck.Read(buf); err == nil || stuck.Err() == nil {
+		t.Error("Pool accepted a stuck registered source")
+	}
+}
@@ -0,0 +1,149 @@
+// glyph_blend.go - Blending several contributing glyphs into one composite glyph
+package coherra
+
+import (
+	"math"
+)
+
+// GlyphSource names where a contributing glyph came from.
+type GlyphSource string
+
+// Common glyph sources; any name may be used.
+const (
+	GlyphSourceOperator      GlyphSource = "operator"
+	GlyphSourcePeerConsensus GlyphSource = "peer-consensus"
+	GlyphSourceMonitor       GlyphSource = "monitor"
+)
+
+// GlyphBlendMode selects how BlendGlyphs combines contributions.
+type GlyphBlendMode string
+
+const (
+	// BlendWeightedAverage averages every contribution's intensity and ethics
+	// score by weight and takes the emotion with the most total weight.
+	BlendWeightedAverage GlyphBlendMode = "weighted-average"
+	// BlendDominantEmotion takes the emotion with the most total weight and
+	// averages only the contributions with that emotion.
+	BlendDominantEmotion GlyphBlendMode = "dominant-emotion"
+)
+
+// DefaultGlyphConflictThreshold is the intensity or ethics gap at which two
+// contributions conflict.
+const DefaultGlyphConflictThreshold = 0.3
+
+var (
+	ErrNoGlyphContributions = &QALXError{"No glyph contributions to blend"}
+	ErrInvalidGlyphWeight   = &QALXError{"Glyph contribution weight must be positive and finite"}
+	ErrUnknownBlendMode     = &QALXError{"Unknown glyph blend mode"}
+)
+
+// GlyphContribution is one glyph offered for blending, with its relative weight.
+type GlyphContribution struct {
+	Source GlyphSource
+	Glyph  LyraGlyph
+	Weight float64
+}
+
+// GlyphProvenance records how a contribution entered a composite glyph.
+type GlyphProvenance struct {
+	GlyphContribution
+	// Share is the contribution's weight as a fraction of the total.
+	Share float64
+	// Used reports whether the contribution shaped the composite's intensity and ethics score.
+	Used bool
+}
+
+// GlyphConflict reports two contributions that disagree. Gaps are absolute differences.
+type GlyphConflict struct {
+	A, B            GlyphSource
+	EmotionMismatch bool
+	IntensityGap    float64
+	EthicsGap       float64
+}
+
+// CompositeGlyph is a blended glyph with the provenance of its parts. Its
+// embedded LyraGlyph can be passed anywhere a glyph is accepted.
+type CompositeGlyph struct {
+	LyraGlyph
+	Mode      GlyphBlendMode
+	Sources   []GlyphProvenance
+	Conflicts []GlyphConflict `json:",omitempty"`
+}
+
+// Glyph returns the blended glyph.
+func (c CompositeGlyph) Glyph() LyraGlyph {
+	return c.LyraGlyph
+}
+
+// Conflicted reports whether any contributions disagreed.
+func (c CompositeGlyph) Conflicted() bool {
+	return len(c.Conflicts) > 0
+}
+
+// DetectGlyphConflicts returns every pair of contributions whose emotions differ
+// or whose intensity or ethics score differ by at least threshold.
+func DetectGlyphConflicts(contributions []GlyphContribution, threshold float64) []GlyphConflict {
+	var conflicts []GlyphConflict
+	for i, a := range contributions {
+		for _, b := range contributions[i+1:] {
+			c := GlyphConflict{
+				A:               a.Source,
+				B:               b.Source,
+				EmotionMismatch: a.Glyph.Emotion != b.Glyph.Emotion,
+				IntensityGap:    math.Abs(a.Glyph.Intensity - b.Glyph.Intensity),
+				EthicsGap:       math.Abs(a.Glyph.EthicsScore - b.Glyph.EthicsScore),
+			}
+			if c.EmotionMismatch || c.IntensityGap >= threshold || c.EthicsGap >= threshold {
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	return conflicts
+}
+
+// BlendGlyphs combines contributions into a composite glyph stamped with the
+// latest contribution's timestamp. Each glyph must pass Validate and each weight
+// must be positive; conflicts are detected at DefaultGlyphConflictThreshold.
+func BlendGlyphs(mode GlyphBlendMode, contributions ...GlyphContribution) (CompositeGlyph, error) {
+	if len(contributions) == 0 {
+		return CompositeGlyph{}, ErrNoGlyphContributions
+	}
+	if mode != BlendWeightedAverage && mode != BlendDominantEmotion {
+		return CompositeGlyph{}, ErrUnknownBlendMode
+	}
+	total := 0.0
+	weights := make(map[string]float64)
+	for _, c := range contributions {
+		if err := c.Glyph.Validate(); err != nil {
+			return CompositeGlyph{}, err
+		}
+		if !(c.Weight > 0) || math.IsInf(c.Weight, 0) {
+			return CompositeGlyph{}, ErrInvalidGlyphWeight
+		}
+		total += c.Weight
+		weights[c.Glyph.Emotion] += c.Weight
+	}
+
+	composite := CompositeGlyph{
+		LyraGlyph: LyraGlyph{Emotion: heaviestEmotion(weights)},
+		Mode:      mode,
+		Sources:   make([]GlyphProvenance, len(contributions)),
+		Conflicts: DetectGlyphConflicts(contributions, DefaultGlyphConflictThreshold),
+	}
+	used := 0.0
+	for i, c := range contributions {
+		p := GlyphProvenance{GlyphContribution: c, Share: c.Weight / total}
+		p.Used = mode == BlendWeightedAverage || c.Glyph.Emotion == composite.Emotion
+		if p.Used {
+			used += c.Weight
+			composite.Intensity += c.Weight * c.Glyph.Intensity
+			composite.EthicsScore += c.Weight * c.Glyph.EthicsScore
+		}
+		composite.Timestamp = max(composite.Timestamp, c.Glyph.Timestamp)
+		composite.Sources[i] = p
+	}
+	// Rounding can push a weighted mean of values in [0, 1] just outside it.
+	composite.Intensity = clampUnit(composite.Intensity / used)
+	composite.EthicsScore = clampUnit(composite.EthicsScore / used)
+	return composite, nil
+}
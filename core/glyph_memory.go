@@ -0,0 +1,204 @@
+// glyph_memory.go - Bounded per-node memory of validation outcomes
+package coherra
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"sync"
+)
+
+// DefaultGlyphMemoryCapacity is the number of outcomes a node remembers.
+const DefaultGlyphMemoryCapacity = 64
+
+// GlyphOutcome is one validation of a node. QRE is the node's ComputeQRE score
+// under the validating glyph (0 if it was not finite). Timestamp is when the
+// validation ran: the network clock for validations run by a MeshNetwork, the
+// glyph's timestamp for ValidateMeshNode.
+type GlyphOutcome struct {
+	Passed    bool
+	QRE       float64
+	Timestamp int64
+}
+
+// GlyphMemory is a ring buffer of a node's most recent validation outcomes.
+// It is safe for concurrent use.
+// The zero value is an empty memory of DefaultGlyphMemoryCapacity.
+type GlyphMemory struct {
+	mu       sync.Mutex
+	capacity int
+	buf      []GlyphOutcome
+	next     int
+}
+
+// NewGlyphMemory creates a memory holding up to capacity outcomes; capacity < 1
+// takes DefaultGlyphMemoryCapacity.
+func NewGlyphMemory(capacity int) *GlyphMemory {
+	if capacity < 1 {
+		capacity = DefaultGlyphMemoryCapacity
+	}
+	return &GlyphMemory{capacity: capacity}
+}
+
+// Record adds an outcome, overwriting the oldest once the memory is full.
+func (gm *GlyphMemory) Record(o GlyphOutcome) {
+	if math.IsNaN(o.QRE) || math.IsInf(o.QRE, 0) {
+		o.QRE = 0
+	}
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if gm.capacity == 0 {
+		gm.capacity = DefaultGlyphMemoryCapacity
+	}
+	if len(gm.buf) < gm.capacity {
+		gm.buf = append(gm.buf, o)
+		return
+	}
+	gm.buf[gm.next] = o
+	gm.next = (gm.next + 1) % gm.capacity
+}
+
+// clone returns an independent copy of the memory; a nil memory clones to nil.
+func (gm *GlyphMemory) clone() *GlyphMemory {
+	if gm == nil {
+		return nil
+	}
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	return &GlyphMemory{capacity: gm.capacity, buf: slices.Clone(gm.buf), next: gm.next}
+}
+
+// outcomesLocked returns the outcomes, oldest first.
+func (gm *GlyphMemory) outcomesLocked() []GlyphOutcome {
+	out := make([]GlyphOutcome, 0, len(gm.buf))
+	out = append(out, gm.buf[gm.next:]...)
+	return append(out, gm.buf[:gm.next]...)
+}
+
+// Outcomes returns the remembered outcomes, oldest first.
+func (gm *GlyphMemory) Outcomes() []GlyphOutcome {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	return gm.outcomesLocked()
+}
+
+// Len returns the number of remembered outcomes.
+func (gm *GlyphMemory) Len() int {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	return len(gm.buf)
+}
+
+// Capacity returns the maximum number of outcomes remembered.
+func (gm *GlyphMemory) Capacity() int {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	return gm.capacityLocked()
+}
+
+func (gm *GlyphMemory) capacityLocked() int {
+	if gm.capacity == 0 {
+		return DefaultGlyphMemoryCapacity
+	}
+	return gm.capacity
+}
+
+// successRate returns the fraction of passed outcomes, or false if there are none.
+func successRate(outcomes []GlyphOutcome) (float64, bool) {
+	if len(outcomes) == 0 {
+		return 0, false
+	}
+	passed := 0
+	for _, o := range outcomes {
+		if o.Passed {
+			passed++
+		}
+	}
+	return float64(passed) / float64(len(outcomes)), true
+}
+
+// SuccessRate returns the fraction of the last window outcomes that passed;
+// window <= 0 covers the whole memory. It is false if nothing is remembered.
+func (gm *GlyphMemory) SuccessRate(window int) (float64, bool) {
+	outcomes := gm.Outcomes()
+	if window > 0 && window < len(outcomes) {
+		outcomes = outcomes[len(outcomes)-window:]
+	}
+	return successRate(outcomes)
+}
+
+// Streak returns the outcome of the latest validation and how many validations
+// in a row, ending with the latest, had that outcome.
+func (gm *GlyphMemory) Streak() (passed bool, length int) {
+	outcomes := gm.Outcomes()
+	if len(outcomes) == 0 {
+		return false, 0
+	}
+	passed = outcomes[len(outcomes)-1].Passed
+	for i := len(outcomes) - 1; i >= 0 && outcomes[i].Passed == passed; i-- {
+		length++
+	}
+	return passed, length
+}
+
+// Trend returns the least-squares slope of QRE per validation across the memory.
+// It needs at least two outcomes.
+func (gm *GlyphMemory) Trend() (float64, bool) {
+	return qreTrend(gm.Outcomes())
+}
+
+func qreTrend(outcomes []GlyphOutcome) (float64, bool) {
+	xs := make([]float64, len(outcomes))
+	ys := make([]float64, len(outcomes))
+	for i, o := range outcomes {
+		xs[i] = float64(i)
+		ys[i] = o.QRE
+	}
+	return leastSquaresSlope(xs, ys)
+}
+
+// Improving reports whether the latest validation passed and the node is doing
+// better than before: the newer half of the memory has a higher success rate
+// than the older half, or the same rate with a rising QRE trend.
+func (gm *GlyphMemory) Improving() bool {
+	outcomes := gm.Outcomes()
+	n := len(outcomes)
+	if n < 2 || !outcomes[n-1].Passed {
+		return false
+	}
+	older, _ := successRate(outcomes[:n/2])
+	newer, _ := successRate(outcomes[n/2:])
+	if newer != older {
+		return newer > older
+	}
+	trend, _ := qreTrend(outcomes)
+	return trend > 0
+}
+
+type glyphMemoryJSON struct {
+	Capacity int
+	Outcomes []GlyphOutcome
+}
+
+// MarshalJSON encodes the capacity and the outcomes, oldest first.
+func (gm *GlyphMemory) MarshalJSON() ([]byte, error) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	return json.Marshal(glyphMemoryJSON{Capacity: gm.capacityLocked(), Outcomes: gm.outcomesLocked()})
+}
+
+// UnmarshalJSON restores a memory written by MarshalJSON.
+func (gm *GlyphMemory) UnmarshalJSON(data []byte) error {
+	var v glyphMemoryJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	restored := NewGlyphMemory(v.Capacity)
+	for _, o := range v.Outcomes {
+		restored.Record(o)
+	}
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.capacity, gm.buf, gm.next = restored.capacity, restored.buf, restored.next
+	return nil
+}
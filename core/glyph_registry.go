@@ -0,0 +1,324 @@
+// glyph_registry.go - Per-emotion validation, harmonic and modulation profiles
+package coherra
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Emotion names a LyraGlyph emotion.
+type Emotion string
+
+// The built-in emotion vocabulary.
+const (
+	EmotionTrust    Emotion = "trust"
+	EmotionJoy      Emotion = "joy"
+	EmotionFear     Emotion = "fear"
+	EmotionSurprise Emotion = "surprise"
+	EmotionSadness  Emotion = "sadness"
+	EmotionAnger    Emotion = "anger"
+)
+
+// Harmonic generator names usable in a GlyphProfile.
+const (
+	HarmonicsDynamic = "dynamic"
+	HarmonicsStatic  = "static"
+)
+
+// GlyphConfigError reports an invalid glyph profile or registry config.
+type GlyphConfigError struct {
+	Emotion Emotion
+	Reason  string
+}
+
+// Error returns the error message for GlyphConfigError.
+func (e *GlyphConfigError) Error() string {
+	return e.Reason
+}
+
+// GlyphThresholds are the quantum security parameters applied to a glyph.
+type GlyphThresholds struct {
+	// Resonance is the minimum resonance accepted.
+	Resonance float64
+	// Composite must be exceeded by coherence*resonance*ethics*meshScore.
+	Composite float64
+	// MinQRE must be exceeded by ComputeQRE.
+	MinQRE float64
+	// EthicsBoost is added to the glyph's ethics score per unit of mesh score.
+	EthicsBoost float64 `json:",omitempty"`
+	// DriftCompensation scales the composite by up to 1% with the timestamp.
+	DriftCompensation bool `json:",omitempty"`
+	// VolatilityBonus is added to the composite, scaled by |sin(timestamp)|.
+	VolatilityBonus float64 `json:",omitempty"`
+}
+
+// GlyphRelaxedTier replaces the profile's thresholds when both the glyph's
+// intensity and the metrics' coherence are below Below.
+type GlyphRelaxedTier struct {
+	Below float64
+	GlyphThresholds
+}
+
+// ModulationWeights control how a glyph modulates QuantumMetrics. EntropyQuality
+// is multiplied by the glyph's intensity raised to its weight; the other weights
+// scale the amount added from the intensity (Coherence) or ethics score.
+type ModulationWeights struct {
+	EntropyQuality    float64
+	QuantumResistance float64
+	Coherence         float64
+	ValidationScore   float64
+}
+
+// GlyphProfile is the policy for one emotion.
+type GlyphProfile struct {
+	Emotion    Emotion
+	Thresholds GlyphThresholds
+	Relaxed    *GlyphRelaxedTier `json:",omitempty"`
+	Harmonics  string
+	Modulation ModulationWeights
+}
+
+// defaultModulation reproduces the original ModulateQuantumMetricsWithLyra.
+var defaultModulation = ModulationWeights{EntropyQuality: 1, QuantumResistance: 0.01, Coherence: 0.01, ValidationScore: 0.05}
+
+// defaultGlyphThresholds apply to emotions without special handling.
+var defaultGlyphThresholds = GlyphThresholds{Resonance: QREDefaultThreshold, Composite: QREDefaultCompositeThreshold, MinQRE: 2.0}
+
+// DefaultGlyphProfile applies to emotions that are not registered.
+func DefaultGlyphProfile() GlyphProfile {
+	return GlyphProfile{Thresholds: defaultGlyphThresholds, Harmonics: HarmonicsDynamic, Modulation: defaultModulation}
+}
+
+// TrustGlyphProfile is the built-in trust policy: looser thresholds, with a
+// relaxed tier for low-intensity glyphs on low-coherence metrics.
+func TrustGlyphProfile() GlyphProfile {
+	return GlyphProfile{
+		Emotion:    EmotionTrust,
+		Thresholds: GlyphThresholds{Resonance: 0.4, Composite: 0.45, MinQRE: 2.0, EthicsBoost: 0.01},
+		Relaxed: &GlyphRelaxedTier{
+			Below: 0.90,
+			GlyphThresholds: GlyphThresholds{
+				Resonance:         QRETrustThreshold,
+				Composite:         QRETrustCompositeThreshold,
+				MinQRE:            2.0,
+				EthicsBoost:       0.02,
+				DriftCompensation: true,
+				VolatilityBonus:   0.02,
+			},
+		},
+		Harmonics:  HarmonicsDynamic,
+		Modulation: defaultModulation,
+	}
+}
+
+var (
+	harmonicsMu         sync.RWMutex
+	harmonicsGenerators = map[string]func(LyraGlyph) []float64{
+		HarmonicsDynamic: GenerateDynamicHarmonics,
+		HarmonicsStatic:  func(LyraGlyph) []float64 { return GenerateHarmonics() },
+	}
+)
+
+// RegisterHarmonicGenerator makes a harmonic generator available to glyph profiles by name.
+func RegisterHarmonicGenerator(name string, generator func(LyraGlyph) []float64) {
+	harmonicsMu.Lock()
+	defer harmonicsMu.Unlock()
+	harmonicsGenerators[name] = generator
+}
+
+func harmonicGenerator(name string) (func(LyraGlyph) []float64, bool) {
+	harmonicsMu.RLock()
+	defer harmonicsMu.RUnlock()
+	g, ok := harmonicsGenerators[name]
+	return g, ok
+}
+
+// validate checks that a profile can be used.
+func (p GlyphProfile) validate() error {
+	tiers := []GlyphThresholds{p.Thresholds}
+	if p.Relaxed != nil {
+		tiers = append(tiers, p.Relaxed.GlyphThresholds)
+		if math.IsNaN(p.Relaxed.Below) || math.IsInf(p.Relaxed.Below, 0) {
+			return &GlyphConfigError{Emotion: p.Emotion, Reason: fmt.Sprintf("Glyph profile %q has a non-finite relaxed tier bound", p.Emotion)}
+		}
+	}
+	for _, t := range tiers {
+		for _, v := range []float64{t.Resonance, t.Composite, t.MinQRE, t.EthicsBoost, t.VolatilityBonus} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return &GlyphConfigError{Emotion: p.Emotion, Reason: fmt.Sprintf("Glyph profile %q has a non-finite threshold", p.Emotion)}
+			}
+		}
+	}
+	if _, ok := harmonicGenerator(p.Harmonics); !ok {
+		return &GlyphConfigError{Emotion: p.Emotion, Reason: fmt.Sprintf("Glyph profile %q uses unknown harmonic generator %q", p.Emotion, p.Harmonics)}
+	}
+	return nil
+}
+
+// GlyphRegistry maps emotions to their profiles. It is safe for concurrent use.
+type GlyphRegistry struct {
+	mu       sync.RWMutex
+	fallback GlyphProfile
+	profiles map[Emotion]GlyphProfile
+}
+
+// NewGlyphRegistry creates a registry holding the built-in vocabulary: trust with
+// its own thresholds, and the other built-in emotions with the default profile.
+func NewGlyphRegistry() *GlyphRegistry {
+	r := &GlyphRegistry{fallback: DefaultGlyphProfile(), profiles: make(map[Emotion]GlyphProfile)}
+	r.profiles[EmotionTrust] = TrustGlyphProfile()
+	for _, e := range []Emotion{EmotionJoy, EmotionFear, EmotionSurprise, EmotionSadness, EmotionAnger} {
+		p := DefaultGlyphProfile()
+		p.Emotion = e
+		r.profiles[e] = p
+	}
+	return r
+}
+
+var defaultGlyphRegistry = NewGlyphRegistry()
+
+// DefaultGlyphRegistry returns the registry used by QALXValidateQuantumSecurity,
+// ModulateQuantumMetricsWithLyra and the metric initializers.
+func DefaultGlyphRegistry() *GlyphRegistry {
+	return defaultGlyphRegistry
+}
+
+// Register adds or replaces the profile for p.Emotion.
+func (r *GlyphRegistry) Register(p GlyphProfile) error {
+	if p.Emotion == "" {
+		return &GlyphConfigError{Reason: "Glyph profile has no emotion"}
+	}
+	if err := p.validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.profiles[p.Emotion] = p
+	return nil
+}
+
+// Lookup returns the profile registered for emotion.
+func (r *GlyphRegistry) Lookup(emotion Emotion) (GlyphProfile, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.profiles[emotion]
+	return p, ok
+}
+
+// Profile returns the profile for emotion, or the default profile if it is not registered.
+func (r *GlyphRegistry) Profile(emotion Emotion) GlyphProfile {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p, ok := r.profiles[emotion]; ok {
+		return p
+	}
+	p := r.fallback
+	p.Emotion = emotion
+	return p
+}
+
+// Emotions returns the registered emotions in order.
+func (r *GlyphRegistry) Emotions() []Emotion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	emotions := make([]Emotion, 0, len(r.profiles))
+	for e := range r.profiles {
+		emotions = append(emotions, e)
+	}
+	slices.Sort(emotions)
+	return emotions
+}
+
+// GlyphRegistryConfig is the JSON form of a registry. Default, when set,
+// replaces the profile used for unregistered emotions.
+type GlyphRegistryConfig struct {
+	Default  *GlyphProfile `json:",omitempty"`
+	Profiles []GlyphProfile
+}
+
+// LoadConfig applies a JSON GlyphRegistryConfig. Nothing is changed unless every
+// profile in it is valid.
+func (r *GlyphRegistry) LoadConfig(data []byte) error {
+	var cfg GlyphRegistryConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return &GlyphConfigError{Reason: "Malformed glyph registry config: " + err.Error()}
+	}
+	if cfg.Default != nil {
+		if err := cfg.Default.validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range cfg.Profiles {
+		if p.Emotion == "" {
+			return &GlyphConfigError{Reason: "Glyph profile has no emotion"}
+		}
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cfg.Default != nil {
+		r.fallback = *cfg.Default
+	}
+	for _, p := range cfg.Profiles {
+		r.profiles[p.Emotion] = p
+	}
+	return nil
+}
+
+// LoadFile applies the GlyphRegistryConfig stored at path.
+func (r *GlyphRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.LoadConfig(data)
+}
+
+// Harmonics generates harmonics for glyph with its emotion's generator.
+func (r *GlyphRegistry) Harmonics(glyph LyraGlyph) []float64 {
+	g, ok := harmonicGenerator(r.Profile(Emotion(glyph.Emotion)).Harmonics)
+	if !ok {
+		return GenerateDynamicHarmonics(glyph)
+	}
+	return g(glyph)
+}
+
+// Modulate applies glyph to metrics with its emotion's modulation weights.
+func (r *GlyphRegistry) Modulate(metrics *QuantumMetrics, glyph LyraGlyph) {
+	w := r.Profile(Emotion(glyph.Emotion)).Modulation
+	metrics.EntropyQuality *= math.Pow(glyph.Intensity, w.EntropyQuality)
+	metrics.QuantumResistance += glyph.EthicsScore * w.QuantumResistance
+	metrics.Coherence += glyph.Intensity * w.Coherence
+	metrics.ValidationScore += glyph.EthicsScore * w.ValidationScore
+	metrics.Timestamp = glyph.Timestamp
+	metrics.Pattern = metrics.Pattern + ":" + glyph.Emotion
+}
+
+// ValidateQuantumSecurity checks metrics against the thresholds of glyph's emotion.
+func (r *GlyphRegistry) ValidateQuantumSecurity(metrics QuantumMetrics, resonance float64, glyph LyraGlyph, meshScore float64) bool {
+	p := r.Profile(Emotion(glyph.Emotion))
+	t := p.Thresholds
+	if p.Relaxed != nil && glyph.Intensity < p.Relaxed.Below && metrics.Coherence < p.Relaxed.Below {
+		t = p.Relaxed.GlyphThresholds
+	}
+	if t.EthicsBoost != 0 {
+		glyph.EthicsScore += t.EthicsBoost * meshScore
+	}
+	driftComp := 1.0
+	if t.DriftCompensation {
+		driftComp = 1.0 + 0.01*math.Abs(float64(glyph.Timestamp%1000))/1000
+	}
+	volatilityBonus := t.VolatilityBonus * math.Abs(math.Sin(float64(glyph.Timestamp%360)*math.Pi/180))
+	if resonance < t.Resonance {
+		return false
+	}
+	composite := metrics.Coherence*resonance*glyph.EthicsScore*meshScore*driftComp + volatilityBonus
+	qre := ComputeQRE(metrics, glyph, meshScore, resonance)
+	return composite > t.Composite && qre > t.MinQRE
+}
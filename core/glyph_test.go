@@ -0,0 +1,583 @@
+package coherra
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// legacyValidateQuantumSecurity is QALXValidateQuantumSecurity as it was before
+// per-emotion profiles; the default registry must agree with it.
+func legacyValidateQuantumSecurity(metrics QuantumMetrics, resonance float64, glyph LyraGlyph, meshScore float64) bool {
+	threshold := QREDefaultThreshold
+	compositeThreshold := QREDefaultCompositeThreshold
+	volatilityBonus := 0.0
+	driftComp := 1.0
+	if glyph.Emotion == "trust" {
+		if glyph.Intensity < 0.90 && metrics.Coherence < 0.90 {
+			threshold = QRETrustThreshold
+			compositeThreshold = QRETrustCompositeThreshold
+			glyph.EthicsScore += 0.02 * meshScore
+			driftComp = 1.0 + 0.01*math.Abs(float64(glyph.Timestamp%1000))/1000
+			volatilityBonus = 0.02 * math.Abs(math.Sin(float64(glyph.Timestamp%360)*math.Pi/180))
+		} else {
+			threshold = 0.4
+			compositeThreshold = 0.45
+			glyph.EthicsScore += 0.01 * meshScore
+		}
+	}
+	if resonance < threshold {
+		return false
+	}
+	composite := metrics.Coherence*resonance*glyph.EthicsScore*meshScore*driftComp + volatilityBonus
+	qre := ComputeQRE(metrics, glyph, meshScore, resonance)
+	return composite > compositeThreshold && qre > 2.0
+}
+
+func TestGlyphRegistryMatchesLegacyValidation(t *testing.T) {
+	registry := NewGlyphRegistry()
+	checked, passed := 0, 0
+	for _, emotion := range []string{"trust", "joy", "fear", "unlisted"} {
+		for _, intensity := range []float64{0.3, 0.85, 0.95, 1.0} {
+			for _, coherence := range []float64{0.5, 0.89, 0.95} {
+				for _, resonance := range []float64{0.3, 0.38, 0.45, 0.6, 1.0} {
+					for _, ts := range []int64{1234567890, 1700000123} {
+						glyph := LyraGlyph{Emotion: emotion, Intensity: intensity, EthicsScore: 0.9, Timestamp: ts}
+						metrics := InitializeQuantumMetricsWithGlyph(glyph)
+						metrics.Coherence = coherence
+						want := legacyValidateQuantumSecurity(metrics, resonance, glyph, 1.0)
+						if got := registry.ValidateQuantumSecurity(metrics, resonance, glyph, 1.0); got != want {
+							t.Fatalf("%s intensity=%v coherence=%v resonance=%v: got %v, want %v", emotion, intensity, coherence, resonance, got, want)
+						}
+						checked++
+						if want {
+							passed++
+						}
+					}
+				}
+			}
+		}
+	}
+	if passed == 0 || passed == checked {
+		t.Fatalf("grid does not exercise both outcomes: %d of %d passed", passed, checked)
+	}
+}
+
+func TestGlyphRegistryModulateMatchesLegacy(t *testing.T) {
+	glyph := LyraGlyph{Emotion: "joy", Intensity: 0.7, EthicsScore: 0.8, Timestamp: 1234567890}
+	want := InitializeQuantumMetricsWithGlyph(glyph)
+	got := want
+	want.EntropyQuality *= glyph.Intensity
+	want.QuantumResistance += glyph.EthicsScore * 0.01
+	want.Coherence += glyph.Intensity * 0.01
+	want.ValidationScore += glyph.EthicsScore * 0.05
+	want.Timestamp = glyph.Timestamp
+	want.Pattern += ":joy"
+	ModulateQuantumMetricsWithLyra(&got, glyph)
+	if got.EntropyQuality != want.EntropyQuality || got.QuantumResistance != want.QuantumResistance ||
+		got.Coherence != want.Coherence || got.ValidationScore != want.ValidationScore || got.Pattern != want.Pattern {
+		t.Fatalf("modulated metrics = %+v, want %+v", got, want)
+	}
+}
+
+func TestGlyphRegistryLoadFile(t *testing.T) {
+	RegisterHarmonicGenerator("test-flat", func(g LyraGlyph) []float64 { return []float64{g.Intensity} })
+	path := filepath.Join(t.TempDir(), "glyphs.json")
+	config := `{
+		"Profiles": [{
+			"Emotion": "fear",
+			"Thresholds": {"Resonance": 0.9, "Composite": 0.8, "MinQRE": 2},
+			"Harmonics": "test-flat",
+			"Modulation": {"EntropyQuality": 0, "ValidationScore": 0.1}
+		}]
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	registry := NewGlyphRegistry()
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	fear := LyraGlyph{Emotion: "fear", Intensity: 0.6, EthicsScore: 1.0, Timestamp: 1234567890}
+	if h := registry.Harmonics(fear); !slices.Equal(h, []float64{0.6}) {
+		t.Fatalf("harmonics = %v, want [0.6]", h)
+	}
+	metrics := InitializeQuantumMetricsWithGlyph(fear)
+	metrics.Coherence = 1.0
+	if registry.ValidateQuantumSecurity(metrics, 0.85, fear, 1.0) {
+		t.Fatal("fear glyph passed below its configured resonance threshold")
+	}
+	before := metrics.EntropyQuality
+	registry.Modulate(&metrics, fear)
+	if metrics.EntropyQuality != before {
+		t.Fatalf("entropy quality changed with zero weight: %v -> %v", before, metrics.EntropyQuality)
+	}
+	if !slices.Equal(registry.Emotions(), []Emotion{EmotionAnger, EmotionFear, EmotionJoy, EmotionSadness, EmotionSurprise, EmotionTrust}) {
+		t.Fatalf("emotions = %v", registry.Emotions())
+	}
+	// The default registry is untouched.
+	if p := DefaultGlyphRegistry().Profile(EmotionFear); p.Thresholds != defaultGlyphThresholds {
+		t.Fatalf("default registry fear thresholds = %+v", p.Thresholds)
+	}
+}
+
+func TestGlyphRegistryRejectsInvalidConfig(t *testing.T) {
+	registry := NewGlyphRegistry()
+	err := registry.LoadConfig([]byte(`{"Profiles": [
+		{"Emotion": "joy", "Thresholds": {"Resonance": 0.1}, "Harmonics": "dynamic"},
+		{"Emotion": "surprise", "Harmonics": "missing"}
+	]}`))
+	var configErr *GlyphConfigError
+	if !errors.As(err, &configErr) || configErr.Emotion != EmotionSurprise {
+		t.Fatalf("err = %v, want *GlyphConfigError for surprise", err)
+	}
+	if p, _ := registry.Lookup(EmotionJoy); p.Thresholds != defaultGlyphThresholds {
+		t.Fatalf("joy was changed by a rejected config: %+v", p.Thresholds)
+	}
+	if err := registry.LoadConfig([]byte(`{`)); !errors.As(err, &configErr) {
+		t.Fatalf("err = %v, want *GlyphConfigError", err)
+	}
+	if err := registry.Register(GlyphProfile{Harmonics: HarmonicsDynamic}); !errors.As(err, &configErr) {
+		t.Fatalf("err = %v, want *GlyphConfigError for missing emotion", err)
+	}
+}
+
+func TestLyraGlyphValidate(t *testing.T) {
+	if err := (LyraGlyph{Emotion: "trust", Intensity: 1, EthicsScore: 0, Timestamp: 1}).Validate(); err != nil {
+		t.Fatalf("valid glyph rejected: %v", err)
+	}
+	cases := []struct {
+		glyph  LyraGlyph
+		fields []string
+	}{
+		{LyraGlyph{Emotion: " ", Intensity: 0.5, EthicsScore: 0.5, Timestamp: 1}, []string{"Emotion"}},
+		{LyraGlyph{Emotion: "joy", Intensity: -5, EthicsScore: 0.5, Timestamp: 1}, []string{"Intensity"}},
+		{LyraGlyph{Emotion: "joy", Intensity: math.Inf(1), EthicsScore: math.NaN(), Timestamp: 1}, []string{"Intensity", "EthicsScore"}},
+		{LyraGlyph{Emotion: "joy", Intensity: 0.5, EthicsScore: 1.01, Timestamp: 0}, []string{"EthicsScore", "Timestamp"}},
+		{LyraGlyph{}, []string{"Emotion", "Timestamp"}},
+	}
+	for _, tc := range cases {
+		var validationErr *GlyphValidationError
+		if err := tc.glyph.Validate(); !errors.As(err, &validationErr) {
+			t.Fatalf("%+v: err = %v, want *GlyphValidationError", tc.glyph, err)
+		}
+		var fields []string
+		for _, f := range validationErr.Fields {
+			fields = append(fields, f.Field)
+		}
+		if !slices.Equal(fields, tc.fields) {
+			t.Errorf("%+v: invalid fields = %v, want %v", tc.glyph, fields, tc.fields)
+		}
+		if _, ok := validationErr.Field(tc.fields[0]); !ok {
+			t.Errorf("Field(%q) not found", tc.fields[0])
+		}
+	}
+}
+
+func TestNewLyraGlyphAndSanitized(t *testing.T) {
+	if _, err := NewLyraGlyph("fear", 2, 0.5, 1234567890); err == nil {
+		t.Fatal("NewLyraGlyph accepted intensity 2")
+	}
+	g, err := NewLyraGlyph("fear", 0.2, 0.5, 1234567890)
+	if err != nil || g.Intensity != 0.2 {
+		t.Fatalf("NewLyraGlyph = %+v, %v", g, err)
+	}
+	opts := NewDeterministicOptions([32]byte{}, time.Unix(1700000000, 0))
+	if g, err := opts.NewLyraGlyph("fear", 0.2, 0.5, 0); err != nil || g.Timestamp != 1700000000 {
+		t.Fatalf("Options.NewLyraGlyph = %+v, %v", g, err)
+	}
+
+	s := LyraGlyph{Emotion: " joy ", Intensity: -5, EthicsScore: math.NaN(), Timestamp: 1}.Sanitized()
+	if s != (LyraGlyph{Emotion: "joy", Intensity: 0, EthicsScore: 0, Timestamp: 1}) {
+		t.Fatalf("Sanitized = %+v", s)
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("sanitized glyph invalid: %v", err)
+	}
+	if s := (LyraGlyph{Emotion: "joy", Intensity: math.Inf(1), EthicsScore: 3}).Sanitized(); s.Intensity != 1 || s.EthicsScore != 1 {
+		t.Fatalf("Sanitized did not clamp to 1: %+v", s)
+	}
+}
+
+func TestInvalidGlyphRejected(t *testing.T) {
+	valid := LyraGlyph{Emotion: "trust", Intensity: 1, EthicsScore: 1, Timestamp: 1234567890}
+	metrics := InitializeQuantumMetricsWithGlyph(valid)
+	node := GenerateMeshNode(metrics)
+	bad := valid
+	bad.EthicsScore = math.NaN()
+
+	var validationErr *GlyphValidationError
+	if _, err := QALXGenerateSecureKey(metrics, bad, 1.0); !errors.As(err, &validationErr) {
+		t.Fatalf("QALXGenerateSecureKey err = %v, want *GlyphValidationError", err)
+	}
+	if err := ValidateMeshNode(node, 1.0, bad, 1.0); !errors.As(err, &validationErr) {
+		t.Fatalf("ValidateMeshNode err = %v, want *GlyphValidationError", err)
+	}
+	if err := ValidateMeshNode(node, 1.0, valid, 1.0); err != nil {
+		t.Fatalf("ValidateMeshNode rejected a valid glyph: %v", err)
+	}
+}
+
+func TestValidateAllNodesWithoutNodeTimestamp(t *testing.T) {
+	opts := NewDeterministicOptions([32]byte{}, time.Unix(1700000000, 0))
+	net := opts.NewMeshNetwork()
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1, EthicsScore: 1, Timestamp: 1700000000}
+	node := opts.GenerateMeshNode(opts.InitializeQuantumMetricsWithGlyph(glyph))
+	node.Timestamp = 0
+	net.AddNode(node)
+	if err := net.ValidateAllNodes()[node.ID]; err != nil {
+		t.Fatalf("node without timestamp failed validation: %v", err)
+	}
+}
+
+func TestGlyphTimelineSmoothingAndSwings(t *testing.T) {
+	tl := NewGlyphTimeline(GlyphTimelineConfig{HalfLife: time.Hour, SwingThreshold: 0.3})
+	const start = 1700000000
+	if _, err := tl.Record("node-a", LyraGlyph{Emotion: "trust", Intensity: 0.8, EthicsScore: 0.9, Timestamp: start}); err != nil {
+		t.Fatal(err)
+	}
+	// One half-life later the smoothed value moves halfway to the new sample.
+	swing, err := tl.Record("node-a", LyraGlyph{Emotion: "trust", Intensity: 0.6, EthicsScore: 0.9, Timestamp: start + 3600})
+	if err != nil || swing != nil {
+		t.Fatalf("Record = %v, %v; want no swing", swing, err)
+	}
+	if i, e, _ := tl.Smoothed("node-a"); math.Abs(i-0.7) > 1e-12 || e != 0.9 {
+		t.Fatalf("smoothed = %v, %v; want 0.7, 0.9", i, e)
+	}
+	swing, err = tl.Record("node-a", LyraGlyph{Emotion: "fear", Intensity: 0.2, EthicsScore: 0.9, Timestamp: start + 7200})
+	if err != nil || swing == nil {
+		t.Fatalf("Record = %v, %v; want a swing", swing, err)
+	}
+	if swing.From != EmotionTrust || swing.To != EmotionFear || math.Abs(swing.IntensityDelta+0.5) > 1e-12 {
+		t.Fatalf("swing = %+v", swing)
+	}
+	if len(tl.Swings("node-a")) != 1 {
+		t.Fatalf("swings = %v", tl.Swings("node-a"))
+	}
+
+	if _, err := tl.Record("node-a", LyraGlyph{Emotion: "joy", Intensity: 0.5, EthicsScore: 0.5, Timestamp: start}); err != ErrGlyphOutOfOrder {
+		t.Fatalf("err = %v, want ErrGlyphOutOfOrder", err)
+	}
+	var validationErr *GlyphValidationError
+	if _, err := tl.Record("node-a", LyraGlyph{Emotion: "joy", Intensity: -1, Timestamp: start + 9000}); !errors.As(err, &validationErr) {
+		t.Fatalf("err = %v, want *GlyphValidationError", err)
+	}
+
+	// The latest fear sample (weight 1) outweighs the older trust samples (0.25 + 0.5).
+	g, ok := tl.WeightedGlyph("node-a")
+	if !ok || g.Emotion != "fear" || g.Timestamp != start+7200 {
+		t.Fatalf("WeightedGlyph = %+v, %v", g, ok)
+	}
+	metrics := InitializeQuantumMetricsWithGlyph(g)
+	want := metrics
+	ModulateQuantumMetricsWithLyra(&want, g)
+	if !tl.ModulateQuantumMetrics("node-a", &metrics) || metrics.ValidationScore != want.ValidationScore || metrics.Pattern != want.Pattern {
+		t.Fatalf("ModulateQuantumMetrics = %+v, want %+v", metrics, want)
+	}
+	if tl.ModulateQuantumMetrics("node-b", &metrics) {
+		t.Fatal("ModulateQuantumMetrics succeeded for a node without glyphs")
+	}
+}
+
+func TestGlyphTimelineRetentionAndDrift(t *testing.T) {
+	tl := NewGlyphTimeline(GlyphTimelineConfig{Retention: 3 * 24 * time.Hour, MaxSamples: 3})
+	const day = 24 * 60 * 60
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Unix()
+	for i := range 6 {
+		g := LyraGlyph{Emotion: "joy", Intensity: 0.9 - 0.1*float64(i), EthicsScore: 0.5, Timestamp: start + int64(i)*day}
+		if _, err := tl.Record("node-a", g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	samples := tl.Samples("node-a")
+	if len(samples) != 3 || samples[0].Timestamp != start+3*day {
+		t.Fatalf("retained %d samples starting at %d", len(samples), samples[0].Timestamp)
+	}
+	drift, ok := tl.Drift("node-a")
+	if !ok || math.Abs(drift.IntensityPerDay+0.1) > 1e-9 || math.Abs(drift.EthicsPerDay) > 1e-12 {
+		t.Fatalf("Drift = %+v, %v", drift, ok)
+	}
+	days := tl.Daily("node-a")
+	if len(days) != 3 || !days[0].Day.Equal(time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)) || days[0].Emotion != EmotionJoy || days[0].Samples != 1 {
+		t.Fatalf("Daily = %+v", days)
+	}
+	// A sample far in the future expires everything before it.
+	if _, err := tl.Record("node-a", LyraGlyph{Emotion: "joy", Intensity: 0.5, EthicsScore: 0.5, Timestamp: start + 30*day}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(tl.Samples("node-a")); n != 1 {
+		t.Fatalf("retained %d samples, want 1", n)
+	}
+	if _, ok := tl.Drift("node-a"); ok {
+		t.Fatal("Drift succeeded with one sample")
+	}
+}
+
+func TestGlyphTimelineSameTimestampBurst(t *testing.T) {
+	tl := NewGlyphTimeline(GlyphTimelineConfig{})
+	const ts = 1700000000
+	if _, err := tl.Record("node-a", LyraGlyph{Emotion: "trust", Intensity: 1, EthicsScore: 1, Timestamp: ts}); err != nil {
+		t.Fatal(err)
+	}
+	swings := 0
+	for range 50 {
+		swing, err := tl.Record("node-a", LyraGlyph{Emotion: "fear", Intensity: 0, EthicsScore: 0, Timestamp: ts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if swing != nil {
+			swings++
+		}
+	}
+	// Same-second samples average like a plain mean: 1 one and 50 zeros.
+	intensity, ethics, _ := tl.Smoothed("node-a")
+	if math.Abs(intensity-1.0/51) > 1e-9 || math.Abs(ethics-1.0/51) > 1e-9 {
+		t.Errorf("Smoothed = %v, %v; want 1/51", intensity, ethics)
+	}
+	// Swings stop once the smoothed profile has caught up with the burst.
+	if swings != 3 {
+		t.Errorf("swings = %d, want 3", swings)
+	}
+	if g, _ := tl.WeightedGlyph("node-a"); g.Emotion != "fear" || g.Intensity > 0.05 {
+		t.Errorf("WeightedGlyph = %+v", g)
+	}
+}
+
+func TestGlyphMemoryRingBuffer(t *testing.T) {
+	gm := NewGlyphMemory(4)
+	for i, passed := range []bool{false, false, true, false, true, true} {
+		gm.Record(GlyphOutcome{Passed: passed, QRE: float64(i), Timestamp: int64(1000 + i)})
+	}
+	outcomes := gm.Outcomes()
+	if len(outcomes) != 4 || outcomes[0].Timestamp != 1002 || outcomes[3].Timestamp != 1005 {
+		t.Fatalf("outcomes = %+v, want the last four oldest first", outcomes)
+	}
+	if rate, ok := gm.SuccessRate(0); !ok || rate != 0.75 {
+		t.Fatalf("SuccessRate(0) = %v, %v; want 0.75", rate, ok)
+	}
+	if rate, _ := gm.SuccessRate(3); math.Abs(rate-2.0/3) > 1e-12 {
+		t.Fatalf("SuccessRate(3) = %v, want 2/3", rate)
+	}
+	if passed, n := gm.Streak(); !passed || n != 2 {
+		t.Fatalf("Streak = %v, %d; want true, 2", passed, n)
+	}
+	if trend, ok := gm.Trend(); !ok || math.Abs(trend-1) > 1e-12 {
+		t.Fatalf("Trend = %v, %v; want 1", trend, ok)
+	}
+
+	data, err := json.Marshal(gm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored GlyphMemory
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Capacity() != 4 || !slices.Equal(restored.Outcomes(), outcomes) {
+		t.Fatalf("restored %d/%v, want %v", restored.Capacity(), restored.Outcomes(), outcomes)
+	}
+
+	empty := NewGlyphMemory(0)
+	if _, ok := empty.SuccessRate(0); ok || empty.Capacity() != DefaultGlyphMemoryCapacity {
+		t.Fatal("empty memory reported a success rate or wrong capacity")
+	}
+	if passed, n := empty.Streak(); passed || n != 0 {
+		t.Fatalf("empty Streak = %v, %d", passed, n)
+	}
+}
+
+func TestGlyphMemoryImproving(t *testing.T) {
+	cases := []struct {
+		outcomes []bool
+		qre      []float64
+		want     bool
+	}{
+		{[]bool{true}, []float64{3}, false},
+		{[]bool{false, true}, []float64{3, 3}, true},
+		// The old check treated two passes as improving regardless of scores.
+		{[]bool{true, true}, []float64{3, 2}, false},
+		{[]bool{true, true}, []float64{2, 3}, true},
+		{[]bool{true, true, false, true}, []float64{3, 3, 3, 3}, false},
+		{[]bool{false, false, true, true}, []float64{3, 3, 3, 3}, true},
+		{[]bool{false, true, true, false}, []float64{1, 2, 3, 4}, false},
+	}
+	for _, tc := range cases {
+		gm := NewGlyphMemory(8)
+		for i, passed := range tc.outcomes {
+			gm.Record(GlyphOutcome{Passed: passed, QRE: tc.qre[i]})
+		}
+		if got := gm.Improving(); got != tc.want {
+			t.Errorf("%v %v: Improving = %v, want %v", tc.outcomes, tc.qre, got, tc.want)
+		}
+	}
+}
+
+func TestValidateMeshNodeRecordsMemory(t *testing.T) {
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1, EthicsScore: 1, Timestamp: 1234567890}
+	node := GenerateMeshNode(InitializeQuantumMetricsWithGlyph(glyph))
+
+	if err := ValidateMeshNode(node, 1.0, glyph, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateMeshNode(node, 0.0, glyph, 1.0); err == nil {
+		t.Fatal("expected validation failure at zero resonance")
+	}
+	// Invalid glyphs are rejected before validation and are not remembered.
+	_ = ValidateMeshNode(node, 1.0, LyraGlyph{Emotion: "trust", Intensity: 2, EthicsScore: 1, Timestamp: 1}, 1.0)
+
+	outcomes := node.Memory.Outcomes()
+	if len(outcomes) != 2 || !outcomes[0].Passed || outcomes[1].Passed || outcomes[0].QRE <= 2 || outcomes[0].Timestamp != glyph.Timestamp {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+}
+
+func TestMeshNetworkRecordsMemory(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	opts := NewDeterministicOptions([32]byte{7}, now)
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := opts.OpenMeshNetwork(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1, EthicsScore: 1, Timestamp: 1234567890}
+	node := opts.GenerateMeshNode(InitializeQuantumMetricsWithGlyph(glyph))
+	net.AddNode(node)
+	bare := node
+	bare.ID, bare.Memory = "bare-node", nil
+	net.AddNode(bare)
+
+	logPath := filepath.Join(dir, storeLogFile)
+	before, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	net.ValidateAllNodes()
+	if after, _ := os.Stat(logPath); after.Size() != before.Size() {
+		t.Errorf("ValidateAllNodes grew the log from %d to %d bytes", before.Size(), after.Size())
+	}
+	if err := net.PropagateMetrics(bare.ID, node.ID); err != nil {
+		t.Fatal(err)
+	}
+	if node.Memory.Len() != 0 {
+		t.Fatalf("network validations reached the caller's memory: %+v", node.Memory.Outcomes())
+	}
+	stored, _ := net.GetNode(node.ID)
+	outcomes := stored.Memory.Outcomes()
+	if len(outcomes) != 2 || !outcomes[0].Passed || !outcomes[1].Passed || outcomes[1].Timestamp != now.Unix() {
+		t.Fatalf("outcomes = %+v, want two passes at %d", outcomes, now.Unix())
+	}
+	// Copies returned by GetNode do not share the network's memory.
+	stored.Memory.Record(GlyphOutcome{})
+	if again, _ := net.GetNode(node.ID); again.Memory.Len() != 2 {
+		t.Fatalf("GetNode copy shares the stored memory: %+v", again.Memory.Outcomes())
+	}
+	if stored, _ := net.GetNode(bare.ID); stored.Memory == nil || stored.Memory.Len() != 1 {
+		t.Fatalf("AddNode did not give the node a memory: %+v", stored.Memory)
+	}
+
+	// Outcomes reach the store with the snapshot Close writes.
+	if err := net.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened := openTestNetwork(t, dir)
+	if restored, _ := reopened.GetNode(node.ID); restored.Memory.Len() != 2 {
+		t.Fatalf("memory was not persisted: %+v", restored.Memory.Outcomes())
+	}
+}
+
+func TestBlendGlyphs(t *testing.T) {
+	operator := GlyphContribution{Source: GlyphSourceOperator, Weight: 2,
+		Glyph: LyraGlyph{Emotion: "trust", Intensity: 0.9, EthicsScore: 1.0, Timestamp: 1700000000}}
+	peers := GlyphContribution{Source: GlyphSourcePeerConsensus, Weight: 1,
+		Glyph: LyraGlyph{Emotion: "trust", Intensity: 0.7, EthicsScore: 0.8, Timestamp: 1700000100}}
+	monitor := GlyphContribution{Source: GlyphSourceMonitor, Weight: 1.5,
+		Glyph: LyraGlyph{Emotion: "fear", Intensity: 0.3, EthicsScore: 0.4, Timestamp: 1700000050}}
+
+	avg, err := BlendGlyphs(BlendWeightedAverage, operator, peers, monitor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if avg.Emotion != "trust" || avg.Timestamp != 1700000100 ||
+		math.Abs(avg.Intensity-(2*0.9+0.7+1.5*0.3)/4.5) > 1e-12 || math.Abs(avg.EthicsScore-(2*1.0+0.8+1.5*0.4)/4.5) > 1e-12 {
+		t.Fatalf("weighted average = %+v", avg.LyraGlyph)
+	}
+	if err := avg.Glyph().Validate(); err != nil {
+		t.Fatalf("composite glyph invalid: %v", err)
+	}
+	if math.Abs(avg.Sources[2].Share-1.5/4.5) > 1e-12 || !avg.Sources[2].Used {
+		t.Fatalf("monitor provenance = %+v", avg.Sources[2])
+	}
+	// The operator and monitor disagree on emotion and values; so do peers and monitor.
+	if !avg.Conflicted() || len(avg.Conflicts) != 2 || avg.Conflicts[0].A != GlyphSourceOperator || !avg.Conflicts[0].EmotionMismatch {
+		t.Fatalf("conflicts = %+v", avg.Conflicts)
+	}
+
+	dominant, err := BlendGlyphs(BlendDominantEmotion, operator, peers, monitor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dominant.Emotion != "trust" || math.Abs(dominant.Intensity-(2*0.9+0.7)/3) > 1e-12 || math.Abs(dominant.EthicsScore-(2*1.0+0.8)/3) > 1e-12 || dominant.Sources[2].Used {
+		t.Fatalf("dominant emotion = %+v, sources %+v", dominant.LyraGlyph, dominant.Sources)
+	}
+
+	// A composite is accepted wherever a glyph is.
+	node := GenerateMeshNode(InitializeQuantumMetricsWithGlyph(dominant.LyraGlyph))
+	if err := ValidateMeshNode(node, 1.0, dominant.LyraGlyph, 1.0); err != nil {
+		t.Fatalf("ValidateMeshNode with composite glyph: %v", err)
+	}
+	if _, err := QALXGenerateSecureKey(node.Metrics, dominant.Glyph(), 1.0); err != nil {
+		t.Fatalf("QALXGenerateSecureKey with composite glyph: %v", err)
+	}
+}
+
+func TestBlendGlyphsErrors(t *testing.T) {
+	valid := GlyphContribution{Source: GlyphSourceOperator, Weight: 1, Glyph: LyraGlyph{Emotion: "joy", Intensity: 0.5, EthicsScore: 0.5, Timestamp: 1}}
+	if _, err := BlendGlyphs(BlendWeightedAverage); err != ErrNoGlyphContributions {
+		t.Errorf("err = %v, want ErrNoGlyphContributions", err)
+	}
+	if _, err := BlendGlyphs("median", valid); err != ErrUnknownBlendMode {
+		t.Errorf("err = %v, want ErrUnknownBlendMode", err)
+	}
+	for _, w := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		bad := valid
+		bad.Weight = w
+		if _, err := BlendGlyphs(BlendWeightedAverage, valid, bad); err != ErrInvalidGlyphWeight {
+			t.Errorf("weight %v: err = %v, want ErrInvalidGlyphWeight", w, err)
+		}
+	}
+	bad := valid
+	bad.Glyph.EthicsScore = math.NaN()
+	var validationErr *GlyphValidationError
+	if _, err := BlendGlyphs(BlendDominantEmotion, valid, bad); !errors.As(err, &validationErr) {
+		t.Errorf("err = %v, want *GlyphValidationError", err)
+	}
+	if c := DetectGlyphConflicts([]GlyphContribution{valid, valid}, DefaultGlyphConflictThreshold); len(c) != 0 {
+		t.Errorf("identical contributions conflict: %+v", c)
+	}
+}
+
+func TestGlyphMemoryZeroValue(t *testing.T) {
+	var gm GlyphMemory
+	for i := range DefaultGlyphMemoryCapacity + 1 {
+		gm.Record(GlyphOutcome{Passed: true, QRE: float64(i)})
+	}
+	if gm.Len() != DefaultGlyphMemoryCapacity || gm.Capacity() != DefaultGlyphMemoryCapacity {
+		t.Fatalf("Len = %d, Capacity = %d; want %d", gm.Len(), gm.Capacity(), DefaultGlyphMemoryCapacity)
+	}
+	if first := gm.Outcomes()[0]; first.QRE != 1 {
+		t.Fatalf("oldest outcome QRE = %v, want 1", first.QRE)
+	}
+	var empty GlyphMemory
+	if data, err := json.Marshal(&empty); err != nil || string(data) != `{"Capacity":64,"Outcomes":[]}` {
+		t.Fatalf("Marshal = %s, %v", data, err)
+	}
+}
@@ -0,0 +1,330 @@
+// glyph_timeline.go - Per-node glyph time series with smoothing, swings and drift
+package coherra
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Defaults for a zero GlyphTimelineConfig.
+const (
+	DefaultGlyphHalfLife       = 24 * time.Hour
+	DefaultGlyphSwingThreshold = 0.3
+	DefaultGlyphRetention      = 30 * 24 * time.Hour
+	DefaultGlyphMaxSamples     = 1024
+)
+
+var ErrGlyphOutOfOrder = &QALXError{"Glyph is older than the node's latest glyph"}
+
+// GlyphTimelineConfig tunes a GlyphTimeline. Zero fields take the defaults.
+type GlyphTimelineConfig struct {
+	// HalfLife is how long it takes a sample's weight in the smoothed values to halve.
+	HalfLife time.Duration
+	// SwingThreshold is how far intensity or ethics score must move away from its
+	// smoothed value for a sample to count as a swing.
+	SwingThreshold float64
+	// Retention drops samples older than this, measured from the node's latest sample.
+	Retention time.Duration
+	// MaxSamples caps the samples kept per node.
+	MaxSamples int
+}
+
+func (c GlyphTimelineConfig) withDefaults() GlyphTimelineConfig {
+	if c.HalfLife <= 0 {
+		c.HalfLife = DefaultGlyphHalfLife
+	}
+	if c.SwingThreshold <= 0 {
+		c.SwingThreshold = DefaultGlyphSwingThreshold
+	}
+	if c.Retention <= 0 {
+		c.Retention = DefaultGlyphRetention
+	}
+	if c.MaxSamples <= 0 {
+		c.MaxSamples = DefaultGlyphMaxSamples
+	}
+	return c
+}
+
+// GlyphSwing is a sample that moved sharply away from the node's smoothed profile.
+// Deltas are the sample's values minus the smoothed values before it arrived.
+type GlyphSwing struct {
+	NodeID         string
+	At             int64
+	From           Emotion
+	To             Emotion
+	IntensityDelta float64
+	EthicsDelta    float64
+}
+
+// GlyphDay summarizes one UTC day of a node's glyphs.
+type GlyphDay struct {
+	Day           time.Time
+	Samples       int
+	Emotion       Emotion
+	MeanIntensity float64
+	MeanEthics    float64
+}
+
+// GlyphDrift is the least-squares trend of a node's glyphs, per day.
+type GlyphDrift struct {
+	IntensityPerDay float64
+	EthicsPerDay    float64
+	From, To        int64
+}
+
+type nodeTimeline struct {
+	samples   []LyraGlyph
+	swings    []GlyphSwing
+	intensity float64
+	ethics    float64
+}
+
+// GlyphTimeline stores a time series of glyphs per node and tracks a time-aware
+// exponentially weighted moving average of intensity and ethics score. Times are
+// the glyphs' Unix-second timestamps; a sample never weighs less than it would
+// in a plain mean of the node's retained samples. It is safe for concurrent use.
+type GlyphTimeline struct {
+	mu    sync.Mutex
+	cfg   GlyphTimelineConfig
+	nodes map[string]*nodeTimeline
+}
+
+// NewGlyphTimeline creates an empty timeline.
+func NewGlyphTimeline(cfg GlyphTimelineConfig) *GlyphTimeline {
+	return &GlyphTimeline{cfg: cfg.withDefaults(), nodes: make(map[string]*nodeTimeline)}
+}
+
+// decay returns the weight left on a value dt seconds old.
+func (tl *GlyphTimeline) decay(dt int64) float64 {
+	return math.Exp(-math.Ln2 * float64(dt) / tl.cfg.HalfLife.Seconds())
+}
+
+// Record adds a glyph to the node's timeline. Glyphs must be valid and arrive in
+// timestamp order. It returns the swing the glyph caused, or nil.
+func (tl *GlyphTimeline) Record(nodeID string, glyph LyraGlyph) (*GlyphSwing, error) {
+	if err := glyph.Validate(); err != nil {
+		return nil, err
+	}
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	n, ok := tl.nodes[nodeID]
+	if !ok {
+		n = &nodeTimeline{intensity: glyph.Intensity, ethics: glyph.EthicsScore}
+		tl.nodes[nodeID] = n
+		n.samples = append(n.samples, glyph)
+		return nil, nil
+	}
+	last := n.samples[len(n.samples)-1]
+	if glyph.Timestamp < last.Timestamp {
+		return nil, ErrGlyphOutOfOrder
+	}
+	var swing *GlyphSwing
+	di, de := glyph.Intensity-n.intensity, glyph.EthicsScore-n.ethics
+	if math.Abs(di) >= tl.cfg.SwingThreshold || math.Abs(de) >= tl.cfg.SwingThreshold {
+		swing = &GlyphSwing{
+			NodeID:         nodeID,
+			At:             glyph.Timestamp,
+			From:           Emotion(last.Emotion),
+			To:             Emotion(glyph.Emotion),
+			IntensityDelta: di,
+			EthicsDelta:    de,
+		}
+		n.swings = append(n.swings, *swing)
+	}
+	// Every sample gets at least the weight it would have in a plain mean of the
+	// retained samples, so glyphs sharing a timestamp are not ignored.
+	alpha := max(1-tl.decay(glyph.Timestamp-last.Timestamp), 1/float64(len(n.samples)+1))
+	n.intensity += alpha * di
+	n.ethics += alpha * de
+	n.samples = append(n.samples, glyph)
+	tl.pruneLocked(n)
+	return swing, nil
+}
+
+// pruneLocked drops samples and swings outside the retention window and sample cap.
+func (tl *GlyphTimeline) pruneLocked(n *nodeTimeline) {
+	cutoff := n.samples[len(n.samples)-1].Timestamp - int64(tl.cfg.Retention/time.Second)
+	i, _ := slices.BinarySearchFunc(n.samples, cutoff, func(g LyraGlyph, t int64) int { return cmp.Compare(g.Timestamp, t) })
+	i = max(i, len(n.samples)-tl.cfg.MaxSamples)
+	if i > 0 {
+		n.samples = slices.Delete(n.samples, 0, i)
+	}
+	j, _ := slices.BinarySearchFunc(n.swings, n.samples[0].Timestamp, func(s GlyphSwing, t int64) int { return cmp.Compare(s.At, t) })
+	if j > 0 {
+		n.swings = slices.Delete(n.swings, 0, j)
+	}
+}
+
+// Nodes returns the IDs of nodes with recorded glyphs, sorted.
+func (tl *GlyphTimeline) Nodes() []string {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	ids := make([]string, 0, len(tl.nodes))
+	for id := range tl.nodes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Samples returns the node's retained glyphs, oldest first.
+func (tl *GlyphTimeline) Samples(nodeID string) []LyraGlyph {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	if n, ok := tl.nodes[nodeID]; ok {
+		return slices.Clone(n.samples)
+	}
+	return nil
+}
+
+// Swings returns the node's retained swings, oldest first.
+func (tl *GlyphTimeline) Swings(nodeID string) []GlyphSwing {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	if n, ok := tl.nodes[nodeID]; ok {
+		return slices.Clone(n.swings)
+	}
+	return nil
+}
+
+// Smoothed returns the node's smoothed intensity and ethics score.
+func (tl *GlyphTimeline) Smoothed(nodeID string) (intensity, ethics float64, ok bool) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	n, ok := tl.nodes[nodeID]
+	if !ok {
+		return 0, 0, false
+	}
+	return n.intensity, n.ethics, true
+}
+
+// WeightedGlyph returns a glyph summarizing the node's recent profile: the
+// smoothed intensity and ethics score, the emotion with the most time-decayed
+// weight, and the latest timestamp.
+func (tl *GlyphTimeline) WeightedGlyph(nodeID string) (LyraGlyph, bool) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	n, ok := tl.nodes[nodeID]
+	if !ok {
+		return LyraGlyph{}, false
+	}
+	latest := n.samples[len(n.samples)-1].Timestamp
+	weights := make(map[string]float64)
+	for _, g := range n.samples {
+		weights[g.Emotion] += tl.decay(latest - g.Timestamp)
+	}
+	return LyraGlyph{
+		Emotion:     heaviestEmotion(weights),
+		Intensity:   n.intensity,
+		EthicsScore: n.ethics,
+		Timestamp:   latest,
+	}, true
+}
+
+// heaviestEmotion returns the emotion with the largest weight, breaking ties by name.
+func heaviestEmotion(weights map[string]float64) string {
+	best, bestWeight := "", math.Inf(-1)
+	for e, w := range weights {
+		if w > bestWeight || (w == bestWeight && e < best) {
+			best, bestWeight = e, w
+		}
+	}
+	return best
+}
+
+// ModulateQuantumMetrics applies the node's WeightedGlyph with
+// ModulateQuantumMetricsWithLyra, reporting whether the node has any glyphs.
+func (tl *GlyphTimeline) ModulateQuantumMetrics(nodeID string, metrics *QuantumMetrics) bool {
+	glyph, ok := tl.WeightedGlyph(nodeID)
+	if ok {
+		ModulateQuantumMetricsWithLyra(metrics, glyph)
+	}
+	return ok
+}
+
+// Daily summarizes the node's retained glyphs per UTC day, oldest first.
+func (tl *GlyphTimeline) Daily(nodeID string) []GlyphDay {
+	var days []GlyphDay
+	var weights map[string]float64
+	finish := func() {
+		d := &days[len(days)-1]
+		d.Emotion = Emotion(heaviestEmotion(weights))
+		d.MeanIntensity /= float64(d.Samples)
+		d.MeanEthics /= float64(d.Samples)
+	}
+	for _, g := range tl.Samples(nodeID) {
+		day := time.Unix(g.Timestamp, 0).UTC().Truncate(24 * time.Hour)
+		if len(days) == 0 || !days[len(days)-1].Day.Equal(day) {
+			if len(days) > 0 {
+				finish()
+			}
+			days = append(days, GlyphDay{Day: day})
+			weights = make(map[string]float64)
+		}
+		d := &days[len(days)-1]
+		d.Samples++
+		d.MeanIntensity += g.Intensity
+		d.MeanEthics += g.EthicsScore
+		weights[g.Emotion]++
+	}
+	if len(days) > 0 {
+		finish()
+	}
+	return days
+}
+
+// Drift fits a least-squares line to the node's retained intensity and ethics
+// scores over time. It needs at least two samples at different times.
+func (tl *GlyphTimeline) Drift(nodeID string) (GlyphDrift, bool) {
+	samples := tl.Samples(nodeID)
+	if len(samples) < 2 {
+		return GlyphDrift{}, false
+	}
+	days := make([]float64, len(samples))
+	intensity := make([]float64, len(samples))
+	ethics := make([]float64, len(samples))
+	for i, g := range samples {
+		days[i] = float64(g.Timestamp-samples[0].Timestamp) / (24 * 60 * 60)
+		intensity[i] = g.Intensity
+		ethics[i] = g.EthicsScore
+	}
+	iSlope, ok := leastSquaresSlope(days, intensity)
+	if !ok {
+		return GlyphDrift{}, false
+	}
+	eSlope, _ := leastSquaresSlope(days, ethics)
+	return GlyphDrift{
+		IntensityPerDay: iSlope,
+		EthicsPerDay:    eSlope,
+		From:            samples[0].Timestamp,
+		To:              samples[len(samples)-1].Timestamp,
+	}, true
+}
+
+// leastSquaresSlope returns the slope of the least-squares line through (xs, ys).
+// It fails when the xs do not vary.
+func leastSquaresSlope(xs, ys []float64) (float64, bool) {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0, false
+	}
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/n, sy/n
+	var sxy, sxx float64
+	for i := range xs {
+		dx := xs[i] - mx
+		sxy += dx * (ys[i] - my)
+		sxx += dx * dx
+	}
+	if sxx == 0 {
+		return 0, false
+	}
+	return sxy / sxx, true
+}
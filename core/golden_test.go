@@ -0,0 +1,120 @@
+package coherra
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// golden_test.go - Deterministic-mode golden file tests. Run with -update to regenerate.
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Reading golden file: %v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output differs from %s:\n got: %s\nwant: %s", path, got, want)
+	}
+}
+
+func deterministicRun(t *testing.T) []byte {
+	t.Helper()
+	var seed [32]byte
+	copy(seed[:], "qalx-golden-seed")
+	opts := NewDeterministicOptions(seed, time.Unix(1234567800, 0).UTC())
+
+	// Timestamp is a multiple of 360 so the harmonics contain no platform-dependent sines.
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: 1234567800}
+	metrics := opts.InitializeQuantumMetricsWithGlyph(glyph)
+	metrics.KeyLength = 32
+	node := opts.GenerateMeshNode(metrics)
+	key, err := opts.GenerateSecureKey(metrics, glyph, DefaultMeshScore)
+	if err != nil {
+		t.Fatalf("GenerateSecureKey: %v", err)
+	}
+	signer, err := NewEd25519Signer(node, opts.Rand)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer: %v", err)
+	}
+	sig, err := QALXSign(signer, key)
+	if err != nil {
+		t.Fatalf("QALXSign: %v", err)
+	}
+	out, err := json.MarshalIndent(struct {
+		Metrics   QuantumMetrics
+		Node      QuantumMeshNode
+		Key       string
+		Signature string
+	}{metrics, node, hex.EncodeToString(key), sig}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(out, '\n')
+}
+
+func TestDeterministicModeGolden(t *testing.T) {
+	first := deterministicRun(t)
+	if second := deterministicRun(t); !bytes.Equal(first, second) {
+		t.Fatal("Deterministic options produced different output across runs")
+	}
+	checkGolden(t, "deterministic.golden", first)
+}
+
+func TestDeterministicOptionsClock(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	opts := NewDeterministicOptions([32]byte{}, now)
+	metrics := opts.InitializeQuantumMetricsWithGlyph(LyraGlyph{Emotion: "joy", Intensity: 0.5, EthicsScore: 0.5})
+	if metrics.Timestamp != now.Unix() {
+		t.Errorf("Metrics timestamp = %d, want clock time %d", metrics.Timestamp, now.Unix())
+	}
+	if node := opts.GenerateMeshNode(QuantumMetrics{}); node.Timestamp != now.Unix() {
+		t.Errorf("Node timestamp = %d, want clock time %d", node.Timestamp, now.Unix())
+	}
+}
+
+func TestGenerateSignatureReaderFailure(t *testing.T) {
+	opts := Options{Rand: iotest.ErrReader(errors.New("entropy pool unhealthy"))}
+	if sig, err := opts.NewSignature(); err == nil {
+		t.Errorf("NewSignature = %q, want the reader's error", sig)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("GenerateSignature did not panic on a failing reader")
+		}
+	}()
+	opts.GenerateSignature()
+}
+
+func TestPackageConstructorsKeepZeroTimestamp(t *testing.T) {
+	metrics := InitializeQuantumMetricsWithGlyph(LyraGlyph{Emotion: "joy", Intensity: 0.5, EthicsScore: 0.5})
+	if metrics.Timestamp != 0 {
+		t.Errorf("Metrics timestamp = %d, want 0", metrics.Timestamp)
+	}
+	if node := GenerateMeshNode(metrics); node.Timestamp != 0 {
+		t.Errorf("Node timestamp = %d, want 0", node.Timestamp)
+	}
+	if sig, err := NewSignature(); err != nil {
+		t.Fatal(err)
+	} else if _, err := uuid.Parse(sig); err != nil {
+		t.Errorf("NewSignature = %q: %v", sig, err)
+	}
+}
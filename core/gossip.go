@@ -0,0 +1,295 @@
+// gossip.go - Push-pull gossip dissemination of metrics across the mesh
+package coherra
+
+import (
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Gossip defaults.
+const (
+	DefaultGossipFanout    = 3
+	DefaultGossipRounds    = 10
+	DefaultGossipTolerance = 1e-3
+)
+
+// GossipConfig tunes a gossip run.
+type GossipConfig struct {
+	// Fanout is the number of peers each node exchanges with per round.
+	Fanout int
+	// Rounds is the maximum number of rounds; a run stops earlier once converged.
+	Rounds int
+	// Tolerance is the largest ValidationScore spread still considered converged.
+	Tolerance float64
+	// Rand selects peers. Nil uses a randomly seeded generator.
+	Rand *rand.Rand
+}
+
+// MetricsUpdate is a versioned QuantumMetrics rumor published by an origin node.
+type MetricsUpdate struct {
+	Origin  string
+	Version uint64
+	Metrics QuantumMetrics
+}
+
+// GossipRound records convergence after one round.
+type GossipRound struct {
+	Round    int
+	Messages int
+	// Coverage is the fraction of (node, update) pairs already delivered.
+	Coverage float64
+	// ScoreSpread is max - min ValidationScore across participating nodes.
+	ScoreSpread float64
+	// ScoreVariance is the population variance of ValidationScore.
+	ScoreVariance float64
+}
+
+// GossipReport summarizes a gossip run.
+type GossipReport struct {
+	Rounds              []GossipRound
+	Converged           bool
+	MeanValidationScore float64
+	// Delivered counts the updates applied to receiving nodes.
+	Delivered int
+	// WriteErrors counts nodes whose results could not be written back,
+	// for example because they were removed during the run.
+	WriteErrors int
+}
+
+// Gossiper disseminates metric updates and averages ValidationScore across a
+// MeshNetwork using push-pull exchanges. Only active nodes participate.
+// When a node receives an update, the origin's Coherence is appended to the
+// node's Metrics.CoherenceHistory, as PropagateMetrics does; the full updates
+// are kept in the gossiper and read with Known.
+type Gossiper struct {
+	network *MeshNetwork
+	config  GossipConfig
+	mu      sync.Mutex
+	known   map[string]map[string]MetricsUpdate
+	version uint64
+}
+
+// NewGossiper creates a gossiper for network, filling unset config fields with defaults.
+func NewGossiper(network *MeshNetwork, config GossipConfig) *Gossiper {
+	if config.Fanout <= 0 {
+		config.Fanout = DefaultGossipFanout
+	}
+	if config.Rounds <= 0 {
+		config.Rounds = DefaultGossipRounds
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = DefaultGossipTolerance
+	}
+	if config.Rand == nil {
+		config.Rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return &Gossiper{network: network, config: config, known: make(map[string]map[string]MetricsUpdate)}
+}
+
+// Publish records new metrics for origin in the network and starts a rumor from it.
+func (g *Gossiper) Publish(originID string, metrics QuantumMetrics) error {
+	metrics = metrics.clone()
+	if err := g.network.update(originID, func(node *QuantumMeshNode) { node.Metrics = metrics }); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.version++
+	g.learn(originID, MetricsUpdate{Origin: originID, Version: g.version, Metrics: metrics})
+	return nil
+}
+
+// Known returns the updates a node has received, ordered by origin.
+func (g *Gossiper) Known(nodeID string) []MetricsUpdate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	updates := make([]MetricsUpdate, 0, len(g.known[nodeID]))
+	for _, u := range g.known[nodeID] {
+		u.Metrics = u.Metrics.clone()
+		updates = append(updates, u)
+	}
+	slices.SortFunc(updates, func(a, b MetricsUpdate) int {
+		if a.Origin < b.Origin {
+			return -1
+		}
+		if a.Origin > b.Origin {
+			return 1
+		}
+		return 0
+	})
+	return updates
+}
+
+// learn stores u at nodeID if it is newer than what the node already has.
+func (g *Gossiper) learn(nodeID string, u MetricsUpdate) {
+	m := g.known[nodeID]
+	if m == nil {
+		m = make(map[string]MetricsUpdate)
+		g.known[nodeID] = m
+	}
+	if cur, ok := m[u.Origin]; !ok || cur.Version < u.Version {
+		m[u.Origin] = u
+	}
+}
+
+// peers lists the gossip partners available to a node. Once the network has
+// links, nodes only gossip with their neighbors.
+func (g *Gossiper) peers(nodeID string, participants []string, linked bool) []string {
+	candidates := participants
+	if linked {
+		candidates = g.network.Neighbors(nodeID)
+	}
+	out := make([]string, 0, len(candidates))
+	for _, id := range candidates {
+		if id != nodeID && slices.Contains(participants, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Run executes gossip rounds until updates reach every participant and
+// ValidationScore has converged, or the round limit is hit. Each node's score
+// then moves by the change averaging made to it, so changes made to the network
+// while the run was in progress are kept, and the updates it received are applied.
+func (g *Gossiper) Run() GossipReport {
+	scores := make(map[string]float64)
+	initial := make(map[string]float64)
+	var participants []string
+	for _, node := range g.network.Snapshot() {
+		if node.State != NodeStateActive {
+			continue
+		}
+		participants = append(participants, node.ID)
+		scores[node.ID] = node.Metrics.ValidationScore
+		initial[node.ID] = node.Metrics.ValidationScore
+	}
+
+	linked := len(g.network.Edges()) > 0
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	before := make(map[string]map[string]MetricsUpdate, len(participants))
+	for _, id := range participants {
+		before[id] = maps.Clone(g.known[id])
+	}
+	report := GossipReport{}
+	if len(participants) < 2 {
+		report.Converged = true
+		report.MeanValidationScore = meanScore(scores)
+		return report
+	}
+	for round := 1; round <= g.config.Rounds; round++ {
+		messages := 0
+		order := slices.Clone(participants)
+		g.config.Rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		for _, id := range order {
+			peers := g.peers(id, participants, linked)
+			g.config.Rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+			for _, peer := range peers[:min(g.config.Fanout, len(peers))] {
+				g.exchange(id, peer)
+				avg := (scores[id] + scores[peer]) / 2
+				scores[id], scores[peer] = avg, avg
+				messages += 2
+			}
+		}
+		stats := GossipRound{Round: round, Messages: messages, Coverage: g.coverage(participants)}
+		stats.ScoreSpread, stats.ScoreVariance = scoreSpread(scores)
+		report.Rounds = append(report.Rounds, stats)
+		if stats.Coverage == 1 && stats.ScoreSpread <= g.config.Tolerance {
+			report.Converged = true
+			break
+		}
+	}
+	for _, id := range participants {
+		delta := scores[id] - initial[id]
+		delivered := g.deliveredSince(id, before[id])
+		err := g.network.update(id, func(node *QuantumMeshNode) {
+			node.Metrics.ValidationScore += delta
+			for _, u := range delivered {
+				node.Metrics.CoherenceHistory = append(node.Metrics.CoherenceHistory, u.Metrics.Coherence)
+			}
+		})
+		if err != nil {
+			report.WriteErrors++
+			continue
+		}
+		report.Delivered += len(delivered)
+	}
+	report.MeanValidationScore = meanScore(scores)
+	return report
+}
+
+// deliveredSince returns the updates from other origins that nodeID learned
+// after before was taken, ordered by origin.
+func (g *Gossiper) deliveredSince(nodeID string, before map[string]MetricsUpdate) []MetricsUpdate {
+	var delivered []MetricsUpdate
+	for origin, u := range g.known[nodeID] {
+		if origin == nodeID {
+			continue
+		}
+		if prev, ok := before[origin]; ok && prev.Version >= u.Version {
+			continue
+		}
+		delivered = append(delivered, u)
+	}
+	slices.SortFunc(delivered, func(a, b MetricsUpdate) int { return strings.Compare(a.Origin, b.Origin) })
+	return delivered
+}
+
+// exchange performs one push-pull: both nodes end with the newest version of every update.
+func (g *Gossiper) exchange(a, b string) {
+	for _, u := range g.known[a] {
+		g.learn(b, u)
+	}
+	for _, u := range g.known[b] {
+		g.learn(a, u)
+	}
+}
+
+// coverage is the fraction of participants holding the newest version of each published update.
+func (g *Gossiper) coverage(participants []string) float64 {
+	latest := make(map[string]uint64)
+	for _, m := range g.known {
+		for origin, u := range m {
+			latest[origin] = max(latest[origin], u.Version)
+		}
+	}
+	if len(latest) == 0 {
+		return 1
+	}
+	have := 0
+	for _, id := range participants {
+		for origin, v := range latest {
+			if g.known[id][origin].Version == v {
+				have++
+			}
+		}
+	}
+	return float64(have) / float64(len(participants)*len(latest))
+}
+
+func meanScore(scores map[string]float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, s := range scores {
+		sum += s
+	}
+	return sum / float64(len(scores))
+}
+
+func scoreSpread(scores map[string]float64) (spread, variance float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	mean := meanScore(scores)
+	for _, s := range scores {
+		lo, hi = math.Min(lo, s), math.Max(hi, s)
+		variance += (s - mean) * (s - mean)
+	}
+	return hi - lo, variance / float64(len(scores))
+}
@@ -0,0 +1,226 @@
+// handshake.go - Hybrid X25519 + ML-KEM key agreement between mesh nodes
+package coherra
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+)
+
+// SessionKeySize is the size in bytes of keys produced by the hybrid handshake.
+const SessionKeySize = 32
+
+const (
+	handshakeDomain = "QALX-HS-v1"
+	handshakeInfo   = "QALX hybrid session v1"
+)
+
+var (
+	ErrHandshakePeerMismatch = &QALXError{"Handshake peer does not match expected mesh node"}
+	ErrHandshakeState        = &QALXError{"Handshake message received out of order"}
+	ErrHandshakeUnsigned     = &QALXError{"Handshake message is missing a required signature"}
+)
+
+// HandshakeHello is the initiator's first message.
+type HandshakeHello struct {
+	NodeID              string
+	Pattern             string
+	X25519Public        []byte
+	KEMScheme           KEMScheme
+	KEMEncapsulationKey []byte
+	Signature           string
+}
+
+// HandshakeResponse is the responder's reply carrying its share and the KEM ciphertext.
+type HandshakeResponse struct {
+	NodeID        string
+	Pattern       string
+	X25519Public  []byte
+	KEMCiphertext []byte
+	Signature     string
+}
+
+// HybridSession is the result of a completed handshake.
+type HybridSession struct {
+	LocalID    string
+	PeerID     string
+	Key        []byte
+	Transcript []byte
+}
+
+// HybridHandshake runs one side of the X25519 + ML-KEM handshake for a local node.
+type HybridHandshake struct {
+	local  QuantumMeshNode
+	signer Signer
+	scheme KEMScheme
+	x25519 *ecdh.PrivateKey
+	kem    *KEMDecapsulationKey
+	hello  *HandshakeHello
+}
+
+// NewHybridHandshake prepares a handshake for the local node using ML-KEM-768.
+// A nil signer produces unsigned messages.
+func NewHybridHandshake(local QuantumMeshNode, signer Signer) *HybridHandshake {
+	return &HybridHandshake{local: local, signer: signer, scheme: KEMMLKEM768}
+}
+
+// WithKEMScheme selects the ML-KEM parameter set offered by the initiator.
+func (h *HybridHandshake) WithKEMScheme(scheme KEMScheme) *HybridHandshake {
+	h.scheme = scheme
+	return h
+}
+
+// Hello starts the handshake as initiator.
+func (h *HybridHandshake) Hello() (*HandshakeHello, error) {
+	if h.hello != nil {
+		return nil, ErrHandshakeState
+	}
+	x, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	kem, err := GenerateKEMKeyPair(h.scheme)
+	if err != nil {
+		return nil, err
+	}
+	hello := &HandshakeHello{
+		NodeID:              h.local.ID,
+		Pattern:             h.local.Pattern,
+		X25519Public:        x.PublicKey().Bytes(),
+		KEMScheme:           h.scheme,
+		KEMEncapsulationKey: kem.EncapsulationKey().Bytes(),
+	}
+	if h.signer != nil {
+		if hello.Signature, err = QALXSign(h.signer, hello.signedBytes()); err != nil {
+			return nil, err
+		}
+	}
+	h.x25519, h.kem, h.hello = x, kem, hello
+	return hello, nil
+}
+
+// Respond answers an initiator's hello from the expected peer node. When verifier
+// is non-nil the hello must carry a valid signature from that peer.
+func (h *HybridHandshake) Respond(hello *HandshakeHello, peer QuantumMeshNode, verifier Verifier) (*HandshakeResponse, *HybridSession, error) {
+	if h.hello != nil {
+		return nil, nil, ErrHandshakeState
+	}
+	if hello.NodeID != peer.ID || hello.Pattern != peer.Pattern {
+		return nil, nil, ErrHandshakePeerMismatch
+	}
+	if err := verifyHandshakeSignature(verifier, hello.signedBytes(), hello.Signature); err != nil {
+		return nil, nil, err
+	}
+	peerX, err := ecdh.X25519().NewPublicKey(hello.X25519Public)
+	if err != nil {
+		return nil, nil, err
+	}
+	ek, err := NewKEMEncapsulationKey(hello.KEMScheme, hello.KEMEncapsulationKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	x, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh, err := x.ECDH(peerX)
+	if err != nil {
+		return nil, nil, err
+	}
+	kemSecret, ct := ek.Encapsulate()
+	resp := &HandshakeResponse{
+		NodeID:        h.local.ID,
+		Pattern:       h.local.Pattern,
+		X25519Public:  x.PublicKey().Bytes(),
+		KEMCiphertext: ct,
+	}
+	transcript := handshakeTranscript(hello, resp)
+	if h.signer != nil {
+		if resp.Signature, err = QALXSign(h.signer, transcript); err != nil {
+			return nil, nil, err
+		}
+	}
+	session, err := deriveHybridSession(h.local.ID, peer.ID, dh, kemSecret, transcript)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, session, nil
+}
+
+// Finish completes the handshake as initiator using the responder's reply.
+func (h *HybridHandshake) Finish(resp *HandshakeResponse, peer QuantumMeshNode, verifier Verifier) (*HybridSession, error) {
+	if h.hello == nil || h.x25519 == nil {
+		return nil, ErrHandshakeState
+	}
+	if resp.NodeID != peer.ID || resp.Pattern != peer.Pattern {
+		return nil, ErrHandshakePeerMismatch
+	}
+	transcript := handshakeTranscript(h.hello, resp)
+	if err := verifyHandshakeSignature(verifier, transcript, resp.Signature); err != nil {
+		return nil, err
+	}
+	peerX, err := ecdh.X25519().NewPublicKey(resp.X25519Public)
+	if err != nil {
+		return nil, err
+	}
+	dh, err := h.x25519.ECDH(peerX)
+	if err != nil {
+		return nil, err
+	}
+	kemSecret, err := h.kem.Decapsulate(resp.KEMCiphertext)
+	if err != nil {
+		return nil, err
+	}
+	h.x25519, h.kem = nil, nil
+	return deriveHybridSession(h.local.ID, peer.ID, dh, kemSecret, transcript)
+}
+
+func verifyHandshakeSignature(verifier Verifier, message []byte, signature string) error {
+	if verifier == nil {
+		return nil
+	}
+	if signature == "" {
+		return ErrHandshakeUnsigned
+	}
+	return QALXVerify(verifier, message, signature)
+}
+
+// signedBytes is the portion of the hello covered by the initiator's signature.
+func (hello *HandshakeHello) signedBytes() []byte {
+	b := []byte(handshakeDomain)
+	b = appendLengthPrefixed(b, []byte(hello.NodeID))
+	b = appendLengthPrefixed(b, []byte(hello.Pattern))
+	b = appendLengthPrefixed(b, []byte(hello.KEMScheme))
+	b = appendLengthPrefixed(b, hello.X25519Public)
+	return appendLengthPrefixed(b, hello.KEMEncapsulationKey)
+}
+
+// handshakeTranscript hashes both nodes' identities and every public handshake value.
+func handshakeTranscript(hello *HandshakeHello, resp *HandshakeResponse) []byte {
+	h := sha512.New()
+	h.Write(hello.signedBytes())
+	b := appendLengthPrefixed(nil, []byte(resp.NodeID))
+	b = appendLengthPrefixed(b, []byte(resp.Pattern))
+	b = appendLengthPrefixed(b, resp.X25519Public)
+	b = appendLengthPrefixed(b, resp.KEMCiphertext)
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+func deriveHybridSession(localID, peerID string, dh, kemSecret, transcript []byte) (*HybridSession, error) {
+	secret := make([]byte, 0, len(dh)+len(kemSecret))
+	secret = append(secret, dh...)
+	secret = append(secret, kemSecret...)
+	key, err := hkdf.Key(sha512.New, secret, transcript, handshakeInfo, SessionKeySize)
+	if err != nil {
+		return nil, err
+	}
+	return &HybridSession{LocalID: localID, PeerID: peerID, Key: key, Transcript: transcript}, nil
+}
+
+func appendLengthPrefixed(b, field []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(field)))
+	return append(b, field...)
+}
@@ -0,0 +1,161 @@
+// kem.go - Post-quantum key encapsulation (ML-KEM) for QALX
+package coherra
+
+import (
+	"crypto/hkdf"
+	"crypto/mlkem"
+	"crypto/sha512"
+	"encoding/binary"
+	"math"
+)
+
+// KEMScheme identifies an ML-KEM parameter set.
+type KEMScheme string
+
+const (
+	KEMMLKEM768  KEMScheme = "ML-KEM-768"
+	KEMMLKEM1024 KEMScheme = "ML-KEM-1024"
+)
+
+// KEMSharedKeySize is the size of an ML-KEM shared secret in bytes.
+const KEMSharedKeySize = mlkem.SharedKeySize
+
+var (
+	ErrUnknownKEMScheme = &QALXError{"Unknown KEM scheme"}
+	ErrKEMKeyScheme     = &QALXError{"KEM key does not match scheme"}
+)
+
+// KEMDecapsulationKey is the private half of an ML-KEM key pair.
+type KEMDecapsulationKey struct {
+	scheme KEMScheme
+	dk768  *mlkem.DecapsulationKey768
+	dk1024 *mlkem.DecapsulationKey1024
+}
+
+// KEMEncapsulationKey is the public half of an ML-KEM key pair.
+type KEMEncapsulationKey struct {
+	scheme KEMScheme
+	ek768  *mlkem.EncapsulationKey768
+	ek1024 *mlkem.EncapsulationKey1024
+}
+
+// GenerateKEMKeyPair generates a new ML-KEM decapsulation key for the given scheme.
+func GenerateKEMKeyPair(scheme KEMScheme) (*KEMDecapsulationKey, error) {
+	switch scheme {
+	case KEMMLKEM768:
+		dk, err := mlkem.GenerateKey768()
+		if err != nil {
+			return nil, err
+		}
+		return &KEMDecapsulationKey{scheme: scheme, dk768: dk}, nil
+	case KEMMLKEM1024:
+		dk, err := mlkem.GenerateKey1024()
+		if err != nil {
+			return nil, err
+		}
+		return &KEMDecapsulationKey{scheme: scheme, dk1024: dk}, nil
+	}
+	return nil, ErrUnknownKEMScheme
+}
+
+// NewKEMDecapsulationKey restores a decapsulation key from its 64-byte seed.
+func NewKEMDecapsulationKey(scheme KEMScheme, seed []byte) (*KEMDecapsulationKey, error) {
+	switch scheme {
+	case KEMMLKEM768:
+		dk, err := mlkem.NewDecapsulationKey768(seed)
+		if err != nil {
+			return nil, err
+		}
+		return &KEMDecapsulationKey{scheme: scheme, dk768: dk}, nil
+	case KEMMLKEM1024:
+		dk, err := mlkem.NewDecapsulationKey1024(seed)
+		if err != nil {
+			return nil, err
+		}
+		return &KEMDecapsulationKey{scheme: scheme, dk1024: dk}, nil
+	}
+	return nil, ErrUnknownKEMScheme
+}
+
+// Scheme returns the ML-KEM parameter set of the key.
+func (k *KEMDecapsulationKey) Scheme() KEMScheme { return k.scheme }
+
+// Bytes returns the seed form of the decapsulation key. Treat it as secret.
+func (k *KEMDecapsulationKey) Bytes() []byte {
+	if k.scheme == KEMMLKEM1024 {
+		return k.dk1024.Bytes()
+	}
+	return k.dk768.Bytes()
+}
+
+// EncapsulationKey returns the public key to hand to peers.
+func (k *KEMDecapsulationKey) EncapsulationKey() *KEMEncapsulationKey {
+	if k.scheme == KEMMLKEM1024 {
+		return &KEMEncapsulationKey{scheme: k.scheme, ek1024: k.dk1024.EncapsulationKey()}
+	}
+	return &KEMEncapsulationKey{scheme: k.scheme, ek768: k.dk768.EncapsulationKey()}
+}
+
+// Decapsulate recovers the shared secret from a ciphertext produced by Encapsulate.
+func (k *KEMDecapsulationKey) Decapsulate(ciphertext []byte) ([]byte, error) {
+	if k.scheme == KEMMLKEM1024 {
+		return k.dk1024.Decapsulate(ciphertext)
+	}
+	return k.dk768.Decapsulate(ciphertext)
+}
+
+// NewKEMEncapsulationKey parses an encoded encapsulation key for the given scheme.
+func NewKEMEncapsulationKey(scheme KEMScheme, encoded []byte) (*KEMEncapsulationKey, error) {
+	switch scheme {
+	case KEMMLKEM768:
+		ek, err := mlkem.NewEncapsulationKey768(encoded)
+		if err != nil {
+			return nil, ErrKEMKeyScheme
+		}
+		return &KEMEncapsulationKey{scheme: scheme, ek768: ek}, nil
+	case KEMMLKEM1024:
+		ek, err := mlkem.NewEncapsulationKey1024(encoded)
+		if err != nil {
+			return nil, ErrKEMKeyScheme
+		}
+		return &KEMEncapsulationKey{scheme: scheme, ek1024: ek}, nil
+	}
+	return nil, ErrUnknownKEMScheme
+}
+
+// Scheme returns the ML-KEM parameter set of the key.
+func (k *KEMEncapsulationKey) Scheme() KEMScheme { return k.scheme }
+
+// Bytes returns the encoded encapsulation key.
+func (k *KEMEncapsulationKey) Bytes() []byte {
+	if k.scheme == KEMMLKEM1024 {
+		return k.ek1024.Bytes()
+	}
+	return k.ek768.Bytes()
+}
+
+// Encapsulate generates a fresh shared secret and the ciphertext that carries it.
+func (k *KEMEncapsulationKey) Encapsulate() (sharedKey, ciphertext []byte) {
+	if k.scheme == KEMMLKEM1024 {
+		return k.ek1024.Encapsulate()
+	}
+	return k.ek768.Encapsulate()
+}
+
+// QALXTweakSharedSecret binds a KEM shared secret to a glyph and mesh score using
+// HKDF-SHA-512. Both parties must apply the same glyph and score to agree on the key.
+func QALXTweakSharedSecret(sharedKey []byte, glyph LyraGlyph, meshScore float64) ([]byte, error) {
+	info := append([]byte("QALX-KEM-v1"), 0)
+	info = appendGlyphContext(info, glyph)
+	info = binary.BigEndian.AppendUint64(info, math.Float64bits(meshScore))
+	return hkdf.Key(sha512.New, sharedKey, nil, string(info), KEMSharedKeySize)
+}
+
+// appendGlyphContext appends an unambiguous encoding of the glyph to a KDF label.
+func appendGlyphContext(b []byte, glyph LyraGlyph) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(glyph.Emotion)))
+	b = append(b, glyph.Emotion...)
+	b = binary.BigEndian.AppendUint64(b, math.Float64bits(glyph.Intensity))
+	b = binary.BigEndian.AppendUint64(b, math.Float64bits(glyph.EthicsScore))
+	return binary.BigEndian.AppendUint64(b, uint64(glyph.Timestamp))
+}
@@ -0,0 +1,238 @@
+// lifecycle.go - Mesh node lifecycle states and enforced transitions
+package coherra
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// NodeState is the lifecycle state of a QuantumMeshNode.
+type NodeState string
+
+const (
+	// NodeStatePending nodes have joined but are not yet trusted.
+	NodeStatePending NodeState = "pending"
+	// NodeStateActive nodes take part in validation, propagation and gossip.
+	NodeStateActive NodeState = "active"
+	// NodeStateQuarantined nodes failed validation and are isolated until released.
+	NodeStateQuarantined NodeState = "quarantined"
+	// NodeStateSuspended nodes were taken out of service by an operator.
+	NodeStateSuspended NodeState = "suspended"
+	// NodeStateRevoked nodes are permanently distrusted.
+	NodeStateRevoked NodeState = "revoked"
+	// NodeStateRetired nodes left the mesh gracefully.
+	NodeStateRetired NodeState = "retired"
+)
+
+// ErrNodeNotActive is returned when a node that is not active is asked to take part in the mesh.
+var ErrNodeNotActive = &QALXError{"Mesh node is not active"}
+
+// MaxStateHistory is the number of transitions a node's StateHistory keeps;
+// older ones are dropped.
+const MaxStateHistory = 64
+
+// nodeTransitions lists the states each state may move to. Revoked is terminal;
+// a retired node can still be revoked if its keys are later compromised.
+var nodeTransitions = map[NodeState][]NodeState{
+	NodeStatePending:     {NodeStateActive, NodeStateRevoked, NodeStateRetired},
+	NodeStateActive:      {NodeStateQuarantined, NodeStateSuspended, NodeStateRevoked, NodeStateRetired},
+	NodeStateQuarantined: {NodeStateActive, NodeStateSuspended, NodeStateRevoked, NodeStateRetired},
+	NodeStateSuspended:   {NodeStateActive, NodeStateRevoked, NodeStateRetired},
+	NodeStateRetired:     {NodeStateRevoked},
+}
+
+// Valid reports whether s is a lifecycle state.
+func (s NodeState) Valid() bool {
+	_, ok := nodeTransitions[s]
+	return ok || s == NodeStateRevoked
+}
+
+// CanTransition reports whether a node may move from one state to another.
+func CanTransition(from, to NodeState) bool {
+	return slices.Contains(nodeTransitions[from], to)
+}
+
+// StateTransition records one state change. At is in Unix seconds.
+type StateTransition struct {
+	From   NodeState
+	To     NodeState
+	At     int64
+	Reason string `json:",omitempty"`
+}
+
+// StateTransitionError is returned for a transition the lifecycle does not allow.
+type StateTransitionError struct {
+	NodeID string
+	From   NodeState
+	To     NodeState
+	Reason string
+}
+
+// Error returns the error message for StateTransitionError.
+func (e *StateTransitionError) Error() string {
+	return e.Reason
+}
+
+// TransitionHook is called after a node changes state.
+type TransitionHook func(nodeID string, transition StateTransition)
+
+type nodeTransition struct {
+	nodeID     string
+	transition StateTransition
+}
+
+// OnTransition registers a hook called after every state change made through
+// the network. Hooks run without the network lock held, so they may call back
+// into the network; changes made on different goroutines may be reported concurrently.
+func (net *MeshNetwork) OnTransition(hook TransitionHook) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.hooks = append(net.hooks, hook)
+}
+
+// transitionLocked moves node to state to, recording the change in its history.
+func (net *MeshNetwork) transitionLocked(node *QuantumMeshNode, to NodeState, reason string) error {
+	if !CanTransition(node.State, to) {
+		return &StateTransitionError{
+			NodeID: node.ID,
+			From:   node.State,
+			To:     to,
+			Reason: fmt.Sprintf("Mesh node cannot move from %s to %s", node.State, to),
+		}
+	}
+	t := StateTransition{From: node.State, To: to, At: net.clock().Unix(), Reason: reason}
+	node.State = to
+	node.StateHistory = append(node.StateHistory, t)
+	if n := len(node.StateHistory); n > MaxStateHistory {
+		node.StateHistory = slices.Clone(node.StateHistory[n-MaxStateHistory:])
+	}
+	net.markReconfig()
+	net.events = append(net.events, nodeTransition{nodeID: node.ID, transition: t})
+	return nil
+}
+
+// admitLocked prepares a node about to be stored. A node already in the network
+// keeps its join time, lifecycle state, history and revocation reason, since
+// state changes go through Transition. A new node joins now as pending, with a
+// fresh history; if its state can be reached from pending it then moves there
+// with a recorded transition.
+func (net *MeshNetwork) admitLocked(node QuantumMeshNode) QuantumMeshNode {
+	local, ok := net.nodes[node.ID]
+	if !ok {
+		state := node.State
+		node.JoinedAt = net.clock().Unix()
+		node.State, node.StateHistory = NodeStatePending, nil
+		if net.transitionLocked(&node, state, "joined") == nil && state == NodeStateRevoked {
+			node = revoked(node, node.RevocationReason)
+		}
+		return node
+	}
+	node.JoinedAt = local.JoinedAt
+	node.State, node.StateHistory, node.RevocationReason = local.State, local.StateHistory, local.RevocationReason
+	if local.State == NodeStateRevoked {
+		node = revoked(node, local.RevocationReason)
+	}
+	return node
+}
+
+// checkParticipatingLocked returns ErrNodeNotActive if the node is in the network
+// in a state that takes it out of the mesh. Unknown and pending nodes pass.
+func (net *MeshNetwork) checkParticipatingLocked(nodeID string) error {
+	node, ok := net.nodes[nodeID]
+	if !ok || node.State == NodeStateActive || node.State == NodeStatePending {
+		return nil
+	}
+	return ErrNodeNotActive
+}
+
+// revokeLocked revokes a stored node unless it is already revoked.
+func (net *MeshNetwork) revokeLocked(node QuantumMeshNode, reason string) {
+	if node.State == NodeStateRevoked {
+		return
+	}
+	node = node.clone()
+	if err := net.transitionLocked(&node, NodeStateRevoked, reason); err != nil {
+		// Nodes in states outside the lifecycle are still revoked, without history.
+		net.markReconfig()
+	}
+	net.putNodeLocked(revoked(node, reason))
+}
+
+// Transition moves a node to a new state, enforcing the lifecycle. Moving a
+// node to revoked is RevokeNode, except that the node must be in the network.
+func (net *MeshNetwork) Transition(nodeID string, to NodeState, reason string) error {
+	net.mu.Lock()
+	defer net.unlock()
+	node, ok := net.nodes[nodeID]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if to == NodeStateRevoked {
+		net.recordRevocationLocked(nodeID, RevocationUnspecified, reason)
+		net.revokeLocked(node, reason)
+		return nil
+	}
+	node = node.clone()
+	if err := net.transitionLocked(&node, to, reason); err != nil {
+		return err
+	}
+	net.putNodeLocked(node)
+	return nil
+}
+
+// Quarantine isolates a node without revoking it. Release it with Transition(id, NodeStateActive, reason).
+func (net *MeshNetwork) Quarantine(nodeID, reason string) error {
+	return net.Transition(nodeID, NodeStateQuarantined, reason)
+}
+
+// QuarantineFailingNodes validates every node and quarantines active nodes that
+// fail ValidateMeshNode. It returns the validation errors of the nodes it quarantined.
+func (net *MeshNetwork) QuarantineFailingNodes() map[string]error {
+	quarantined := make(map[string]error)
+	for id, err := range net.ValidateAllNodes() {
+		var validationErr *MeshNodeValidationError
+		if !errors.As(err, &validationErr) {
+			continue
+		}
+		if node, ok := net.GetNode(id); !ok || node.State != NodeStateActive {
+			continue
+		}
+		if net.Quarantine(id, err.Error()) == nil {
+			quarantined[id] = err
+		}
+	}
+	return quarantined
+}
+
+// uptime returns the fraction of time since the node joined the network that it
+// spent active, replaying its state history up to now. Time before the oldest
+// kept transition counts as spent in that transition's From state. A node with
+// no elapsed time, or no join time, counts as fully up if it is active.
+func uptime(node QuantumMeshNode, now time.Time) float64 {
+	end := now.Unix()
+	start := node.JoinedAt
+	if start <= 0 || end <= start {
+		if node.State == NodeStateActive {
+			return 1
+		}
+		return 0
+	}
+	state := node.State
+	if len(node.StateHistory) > 0 {
+		state = node.StateHistory[0].From
+	}
+	active, at := int64(0), start
+	for _, t := range node.StateHistory {
+		ts := min(max(t.At, start), end)
+		if state == NodeStateActive {
+			active += ts - at
+		}
+		state, at = t.To, ts
+	}
+	if state == NodeStateActive {
+		active += end - at
+	}
+	return float64(active) / float64(end-start)
+}
@@ -3,6 +3,7 @@ package coherra
 
 import (
 	"math"
+	"strings"
 )
 
 var Phi float64 = (1 + math.Sqrt(5)) / 2
@@ -15,6 +16,96 @@ type LyraGlyph struct {
 	Timestamp   int64
 }
 
+// GlyphFieldError describes one invalid LyraGlyph field.
+type GlyphFieldError struct {
+	Field  string
+	Reason string
+}
+
+// GlyphValidationError is returned for a LyraGlyph with invalid fields.
+type GlyphValidationError struct {
+	Fields []GlyphFieldError
+	Reason string
+}
+
+// Error returns the error message for GlyphValidationError.
+func (e *GlyphValidationError) Error() string {
+	return e.Reason
+}
+
+// Field returns the error for the named field, if that field is invalid.
+func (e *GlyphValidationError) Field(name string) (GlyphFieldError, bool) {
+	for _, f := range e.Fields {
+		if f.Field == name {
+			return f, true
+		}
+	}
+	return GlyphFieldError{}, false
+}
+
+// unitInterval reports whether v is finite and in [0, 1].
+func unitInterval(v float64) bool {
+	return v >= 0 && v <= 1
+}
+
+// Validate checks that the glyph has an emotion, an intensity and ethics score
+// in [0, 1] and a positive timestamp. It returns a *GlyphValidationError listing
+// every invalid field.
+func (g LyraGlyph) Validate() error {
+	var fields []GlyphFieldError
+	if strings.TrimSpace(g.Emotion) == "" {
+		fields = append(fields, GlyphFieldError{Field: "Emotion", Reason: "emotion is empty"})
+	}
+	if !unitInterval(g.Intensity) {
+		fields = append(fields, GlyphFieldError{Field: "Intensity", Reason: "intensity must be in [0, 1]"})
+	}
+	if !unitInterval(g.EthicsScore) {
+		fields = append(fields, GlyphFieldError{Field: "EthicsScore", Reason: "ethics score must be in [0, 1]"})
+	}
+	if g.Timestamp <= 0 {
+		fields = append(fields, GlyphFieldError{Field: "Timestamp", Reason: "timestamp must be positive"})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	reasons := make([]string, len(fields))
+	for i, f := range fields {
+		reasons[i] = f.Reason
+	}
+	return &GlyphValidationError{Fields: fields, Reason: "Invalid glyph: " + strings.Join(reasons, "; ")}
+}
+
+// NewLyraGlyph creates a glyph, returning a *GlyphValidationError if any field is invalid.
+func NewLyraGlyph(emotion string, intensity, ethicsScore float64, timestamp int64) (LyraGlyph, error) {
+	g := LyraGlyph{Emotion: emotion, Intensity: intensity, EthicsScore: ethicsScore, Timestamp: timestamp}
+	if err := g.Validate(); err != nil {
+		return LyraGlyph{}, err
+	}
+	return g, nil
+}
+
+// NewLyraGlyph is the strict constructor with a zero timestamp taken from the options' clock.
+func (o Options) NewLyraGlyph(emotion string, intensity, ethicsScore float64, timestamp int64) (LyraGlyph, error) {
+	return NewLyraGlyph(emotion, intensity, ethicsScore, o.timestamp(timestamp))
+}
+
+// Sanitized returns the glyph with its emotion trimmed and its intensity and
+// ethics score clamped to [0, 1]; NaN becomes 0. An empty emotion or a
+// non-positive timestamp cannot be repaired and still fails Validate.
+func (g LyraGlyph) Sanitized() LyraGlyph {
+	g.Emotion = strings.TrimSpace(g.Emotion)
+	g.Intensity = clampUnit(g.Intensity)
+	g.EthicsScore = clampUnit(g.EthicsScore)
+	return g
+}
+
+func clampUnit(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return math.Min(math.Max(v, 0), 1)
+}
+
 // GenerateDynamicHarmonics generates dynamic, glyph-driven harmonics.
 func GenerateDynamicHarmonics(glyph LyraGlyph) []float64 {
 	base := Phi * glyph.Intensity
@@ -31,12 +122,8 @@ func GenerateHarmonics() []float64 {
 	return []float64{1, Phi, math.Pow(Phi, 2), math.Pow(Phi, 3)}
 }
 
-// ModulateQuantumMetricsWithLyra modulates QuantumMetrics using a LyraGlyph.
+// ModulateQuantumMetricsWithLyra modulates QuantumMetrics using a LyraGlyph,
+// weighted by the default registry's profile for its emotion.
 func ModulateQuantumMetricsWithLyra(metrics *QuantumMetrics, glyph LyraGlyph) {
-	metrics.EntropyQuality *= glyph.Intensity
-	metrics.QuantumResistance += glyph.EthicsScore * 0.01
-	metrics.Coherence += glyph.Intensity * 0.01
-	metrics.ValidationScore += glyph.EthicsScore * 0.05
-	metrics.Timestamp = glyph.Timestamp
-	metrics.Pattern = metrics.Pattern + ":" + glyph.Emotion
+	DefaultGlyphRegistry().Modulate(metrics, glyph)
 }
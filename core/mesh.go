@@ -9,7 +9,11 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // DefaultMeshScore is the default mesh score value.
@@ -26,19 +30,48 @@ type QuantumMeshNode struct {
 	Metrics          QuantumMetrics
 	Pattern          string
 	CoherenceHistory []float64
-	State            string
+	State            NodeState
 	Timestamp        int64
+	// RevocationReason records why the node was revoked.
+	RevocationReason string `json:",omitempty"`
+	// StateHistory records every lifecycle transition made through a MeshNetwork.
+	StateHistory []StateTransition `json:",omitempty"`
+	// Memory records the node's validation outcomes.
+	Memory *GlyphMemory `json:",omitempty"`
+	// JoinedAt is when the node joined a MeshNetwork, in Unix seconds by the network's clock.
+	JoinedAt int64 `json:",omitempty"`
+}
+
+// clone returns a copy of the node that shares no slices with the original.
+func (n QuantumMeshNode) clone() QuantumMeshNode {
+	n.Metrics = n.Metrics.clone()
+	n.CoherenceHistory = slices.Clone(n.CoherenceHistory)
+	n.StateHistory = slices.Clone(n.StateHistory)
+	n.Memory = n.Memory.clone()
+	return n
 }
 
 // GenerateMeshNode creates a new QuantumMeshNode using the provided metrics.
+// The node takes the metrics' timestamp as it is.
 func GenerateMeshNode(metrics QuantumMetrics) QuantumMeshNode {
+	return DefaultOptions().generateMeshNode(metrics, metrics.Timestamp)
+}
+
+// GenerateMeshNode creates a node whose ID comes from the options' random reader.
+// Metrics without a timestamp are stamped with the options' clock.
+func (o Options) GenerateMeshNode(metrics QuantumMetrics) QuantumMeshNode {
+	return o.generateMeshNode(metrics, o.timestamp(metrics.Timestamp))
+}
+
+func (o Options) generateMeshNode(metrics QuantumMetrics, timestamp int64) QuantumMeshNode {
 	return QuantumMeshNode{
-		ID:               GenerateSignature(),
+		ID:               o.GenerateSignature(),
 		Metrics:          metrics,
 		Pattern:          GeneratePattern(metrics),
 		CoherenceHistory: append(metrics.CoherenceHistory, metrics.Coherence),
-		State:            "active",
-		Timestamp:        metrics.Timestamp,
+		State:            NodeStateActive,
+		Timestamp:        timestamp,
+		Memory:           NewGlyphMemory(DefaultGlyphMemoryCapacity),
 	}
 }
 
@@ -111,11 +144,35 @@ func (e *MeshNodeValidationError) Error() string {
 
 // ValidateMeshNode checks if a mesh node meets coherence, state, and quantum security requirements.
 // ValidateMeshNode checks if a mesh node meets coherence, state, and quantum security requirements.
-func ValidateMeshNode(node QuantumMeshNode, resonance float64, glyph LyraGlyph, meshScore float64) error {
+// An invalid glyph is rejected with its *GlyphValidationError; otherwise the
+// outcome is recorded in the node's Memory, if it has one, stamped with the
+// glyph's timestamp. A copy of the node held by a MeshNetwork is not updated;
+// the network records its own validations.
+// A node on any of the given revocation lists fails with a *RevokedNodeError,
+// whatever its State; nil lists are skipped.
+func ValidateMeshNode(node QuantumMeshNode, resonance float64, glyph LyraGlyph, meshScore float64, revocations ...*RevocationList) error {
+	if err := glyph.Validate(); err != nil {
+		return err
+	}
+	err := checkRevocationLists(node.ID, revocations)
+	if err == nil {
+		err = validateMeshNode(node, resonance, glyph, meshScore)
+	}
+	if node.Memory != nil {
+		node.Memory.Record(GlyphOutcome{
+			Passed:    err == nil,
+			QRE:       ComputeQRE(node.Metrics, glyph, meshScore, resonance),
+			Timestamp: glyph.Timestamp,
+		})
+	}
+	return err
+}
+
+func validateMeshNode(node QuantumMeshNode, resonance float64, glyph LyraGlyph, meshScore float64) error {
 	if node.Metrics.Coherence < MinCoherence {
 		return &MeshNodeValidationError{Reason: "Mesh node coherence below threshold"}
 	}
-	if node.State != "active" {
+	if node.State != NodeStateActive {
 		return &MeshNodeValidationError{Reason: "Mesh node is not active"}
 	}
 	if !QALXValidateQuantumSecurity(node.Metrics, resonance, glyph, meshScore) {
@@ -151,92 +208,288 @@ func (e *PatternValidationError) Error() string {
 	return e.Reason
 }
 
+// ErrNodeNotFound is returned when a node ID is not part of the mesh network.
+var ErrNodeNotFound = &QALXError{"Node not found in mesh network"}
+
 // MeshNetwork represents a network of quantum mesh nodes.
-// MeshNetwork represents a network of quantum mesh nodes.
+// It is safe for concurrent use; nodes are stored and returned by value
+// as deep copies, so callers never share slices with the network.
 type MeshNetwork struct {
-	Nodes map[string]QuantumMeshNode
+	mu     sync.RWMutex
+	nodes  map[string]QuantumMeshNode
+	edges  map[string]map[string]MeshEdge
+	clock  func() time.Time
+	stats  meshStats
+	crl    *RevocationList
+	hooks  []TransitionHook
+	events []nodeTransition
+
+	patterns *PatternRegistry
+	store    Store
+	records  []StoreRecord
+	crlDirty bool
+	storeErr error
+	// memoryDirty is set when validation outcomes have not been snapshotted yet.
+	memoryDirty bool
+
+	// pathVariance caches PathVariance until the next reconfiguration. Readers
+	// holding only the read lock may fill it.
+	pathVariance atomic.Pointer[float64]
 }
 
-// NewMeshNetwork creates a new mesh network instance.
 // NewMeshNetwork creates a new mesh network instance.
 func NewMeshNetwork() *MeshNetwork {
-	return &MeshNetwork{Nodes: make(map[string]QuantumMeshNode)}
+	return DefaultOptions().NewMeshNetwork()
+}
+
+// NewMeshNetwork creates a mesh network that times reconfigurations with the options' clock.
+func (o Options) NewMeshNetwork() *MeshNetwork {
+	return &MeshNetwork{
+		nodes:    make(map[string]QuantumMeshNode),
+		edges:    make(map[string]map[string]MeshEdge),
+		clock:    o.now,
+		patterns: o.NewPatternRegistry(0),
+	}
 }
 
-// AddNode adds a node to the mesh network.
-// AddNode adds a node to the mesh network.
+// AddNode adds a node to the mesh network, replacing any node with the same ID.
+// A replaced node keeps its lifecycle state and history; use Transition to change
+// them. A new node in a state outside the lifecycle joins pending, and one on the
+// network's revocation list is stored revoked. Nodes without a Memory are given one.
 func (net *MeshNetwork) AddNode(node QuantumMeshNode) {
-	net.Nodes[node.ID] = node
+	net.mu.Lock()
+	defer net.unlock()
+	net.markReconfig()
+	node = net.admitLocked(node.clone())
+	if node.Memory == nil {
+		node.Memory = NewGlyphMemory(DefaultGlyphMemoryCapacity)
+	}
+	net.putNodeLocked(node)
+	net.enforceRevocationsLocked()
+}
+
+// RemoveNode deletes a node and its links from the mesh network and reports whether it was present.
+func (net *MeshNetwork) RemoveNode(nodeID string) bool {
+	net.mu.Lock()
+	defer net.unlock()
+	_, ok := net.nodes[nodeID]
+	if ok {
+		net.markReconfig()
+		net.logLocked(StoreRecord{Op: StoreRemoveNode, NodeID: nodeID})
+	}
+	delete(net.nodes, nodeID)
+	for _, peer := range net.neighborsLocked(nodeID) {
+		net.disconnectLocked(nodeID, peer)
+	}
+	return ok
+}
+
+// GetNode returns a copy of the node with the given ID.
+func (net *MeshNetwork) GetNode(nodeID string) (QuantumMeshNode, bool) {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	node, ok := net.nodes[nodeID]
+	if !ok {
+		return QuantumMeshNode{}, false
+	}
+	return node.clone(), true
+}
+
+// Len returns the number of nodes in the mesh network.
+func (net *MeshNetwork) Len() int {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	return len(net.nodes)
+}
+
+// Snapshot returns copies of all nodes, ordered by ID, taken at a single point in time.
+func (net *MeshNetwork) Snapshot() []QuantumMeshNode {
+	net.mu.RLock()
+	nodes := make([]QuantumMeshNode, 0, len(net.nodes))
+	for _, node := range net.nodes {
+		nodes = append(nodes, node.clone())
+	}
+	net.mu.RUnlock()
+	slices.SortFunc(nodes, func(a, b QuantumMeshNode) int { return strings.Compare(a.ID, b.ID) })
+	return nodes
+}
+
+// Range calls fn for each node of a snapshot until fn returns false.
+// The network is not locked while fn runs, so fn may call back into it.
+func (net *MeshNetwork) Range(fn func(node QuantumMeshNode) bool) {
+	for _, node := range net.Snapshot() {
+		if !fn(node) {
+			return
+		}
+	}
+}
+
+// unlock persists the changes queued while the write lock was held, releases
+// the lock and then runs hooks for the queued transitions.
+func (net *MeshNetwork) unlock() {
+	net.flushLocked()
+	events, hooks := net.events, net.hooks
+	net.events = nil
+	net.mu.Unlock()
+	for _, e := range events {
+		for _, hook := range hooks {
+			hook(e.nodeID, e.transition)
+		}
+	}
+}
+
+// update applies fn to a private copy of the node and stores the result.
+func (net *MeshNetwork) update(nodeID string, fn func(node *QuantumMeshNode)) error {
+	net.mu.Lock()
+	defer net.unlock()
+	node, ok := net.nodes[nodeID]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	node = node.clone()
+	fn(&node)
+	net.putNodeLocked(node)
+	return nil
+}
+
+// UpdateCoherenceHistory appends coherence values to a node in the network.
+func (net *MeshNetwork) UpdateCoherenceHistory(nodeID string, values ...float64) error {
+	return net.update(nodeID, func(node *QuantumMeshNode) {
+		for _, v := range values {
+			UpdateCoherenceHistory(node, v)
+		}
+	})
+}
+
+// updateRemoteCoherence appends coherence values a peer sent for its own node,
+// rejecting a node taken out of the mesh with ErrNodeNotActive.
+func (net *MeshNetwork) updateRemoteCoherence(nodeID string, values []float64) error {
+	net.mu.Lock()
+	defer net.unlock()
+	node, ok := net.nodes[nodeID]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if err := net.checkParticipatingLocked(nodeID); err != nil {
+		return err
+	}
+	node = node.clone()
+	for _, v := range values {
+		UpdateCoherenceHistory(&node, v)
+	}
+	net.putNodeLocked(node)
+	return nil
+}
+
+// mergeRemoteNode stores a node received from a peer. The peer's report of the
+// node's lifecycle is ignored: a new node joins pending, a known node keeps its
+// local state, history and Memory, and one taken out of the mesh is rejected
+// with ErrNodeNotActive.
+func (net *MeshNetwork) mergeRemoteNode(node QuantumMeshNode) error {
+	net.mu.Lock()
+	defer net.unlock()
+	if err := net.checkParticipatingLocked(node.ID); err != nil {
+		return err
+	}
+	if local, ok := net.nodes[node.ID]; ok {
+		node.Memory = local.Memory
+	} else {
+		node.State = NodeStatePending
+	}
+	net.markReconfig()
+	node = net.admitLocked(node.clone())
+	if node.Memory == nil {
+		node.Memory = NewGlyphMemory(DefaultGlyphMemoryCapacity)
+	}
+	net.putNodeLocked(node)
+	net.enforceRevocationsLocked()
+	return nil
 }
 
 // PropagateMetrics updates metrics from one node to another and validates the target node.
-// PropagateMetrics updates metrics from one node to another and validates the target node.
+// Both nodes must be active; otherwise it returns ErrNodeNotActive, or the
+// target's *RevokedNodeError if it is on the revocation list.
 func (net *MeshNetwork) PropagateMetrics(fromID, toID string) error {
-	fromNode, ok1 := net.Nodes[fromID]
-	toNode, ok2 := net.Nodes[toID]
+	net.mu.Lock()
+	fromNode, ok1 := net.nodes[fromID]
+	toNode, ok2 := net.nodes[toID]
 	if !ok1 || !ok2 {
-		return &QALXError{"Node not found in mesh network"}
+		net.unlock()
+		return ErrNodeNotFound
 	}
+	if net.crl != nil {
+		if err := net.crl.Check(toID); err != nil {
+			net.unlock()
+			return err
+		}
+	}
+	// Isolated nodes neither send nor receive metrics.
+	if fromNode.State != NodeStateActive || toNode.State != NodeStateActive {
+		net.unlock()
+		return ErrNodeNotActive
+	}
+	toNode = toNode.clone()
 	toNode.Metrics.CoherenceHistory = append(toNode.Metrics.CoherenceHistory, fromNode.Metrics.Coherence)
 	toNode.Metrics.ValidationScore = (toNode.Metrics.ValidationScore + fromNode.Metrics.ValidationScore) / 2
-	net.Nodes[toID] = toNode
-	defaultGlyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: toNode.Timestamp}
-	// Example: collect metrics (replace with real values as needed)
-	metrics := MeshMetrics{
-		AvgTrustWeight:    0.9,
-		UptimePercent:     99.0,
-		PathVariance:      2.0,
-		GlyphCoherence:    0.95,
-		QREValidationRate: 0.98,
-		ReconfigTime:      1.2,
+	net.putNodeLocked(toNode.clone())
+	net.unlock()
+
+	meshScore := CalculateMeshScore(net.CollectMetrics())
+	return net.validateStoredNode(toNode, meshScore)
+}
+
+// defaultGlyph is the full-trust glyph nodes are validated against, stamped with
+// the node's creation time or, for nodes without one, the network clock.
+func (net *MeshNetwork) defaultGlyph(node QuantumMeshNode) LyraGlyph {
+	ts := node.Timestamp
+	if ts <= 0 {
+		ts = net.clock().Unix()
 	}
-	meshScore := CalculateMeshScore(metrics)
-	return ValidateMeshNode(toNode, DefaultMeshScore, defaultGlyph, meshScore)
+	return LyraGlyph{Emotion: string(EmotionTrust), Intensity: 1.0, EthicsScore: 1.0, Timestamp: ts}
+}
+
+// validateStoredNode validates a copy of a network node against the default
+// glyph and records the outcome.
+func (net *MeshNetwork) validateStoredNode(node QuantumMeshNode, meshScore float64) error {
+	glyph := net.defaultGlyph(node)
+	err := net.checkRevocationList(node.ID)
+	if err == nil {
+		err = validateMeshNode(node, DefaultMeshScore, glyph, meshScore)
+	}
+	net.recordValidation(node, err, ComputeQRE(node.Metrics, glyph, meshScore, DefaultMeshScore))
+	return err
 }
 
-// ValidateAllNodes validates all nodes in the mesh network and returns a map of errors.
 // ValidateAllNodes validates all nodes in the mesh network and returns a map of errors.
 func (net *MeshNetwork) ValidateAllNodes() map[string]error {
 	results := make(map[string]error)
-	for id, node := range net.Nodes {
-		defaultGlyph := LyraGlyph{Emotion: "trust", Intensity: 1.0, EthicsScore: 1.0, Timestamp: node.Timestamp}
-		metrics := MeshMetrics{
-			AvgTrustWeight:    0.9,
-			UptimePercent:     99.0,
-			PathVariance:      2.0,
-			GlyphCoherence:    0.95,
-			QREValidationRate: 0.98,
-			ReconfigTime:      1.2,
-		}
-		meshScore := CalculateMeshScore(metrics)
-		results[id] = ValidateMeshNode(node, DefaultMeshScore, defaultGlyph, meshScore)
+	meshScore := CalculateMeshScore(net.CollectMetrics())
+	for _, node := range net.Snapshot() {
+		results[node.ID] = net.validateStoredNode(node, meshScore)
 	}
+	net.mu.Lock()
+	net.completeReconfig()
+	net.mu.Unlock()
 	return results
 }
 
-// RevokeNode sets the state of a node to revoked and updates its pattern.
-// RevokeNode sets the state of a node to revoked and updates its pattern.
+// RevokeNode sets the state of a node to revoked, records the reason and updates its pattern.
+// When the network has a revocation list the node is added to it, even if it is not
+// currently in the network.
 func (net *MeshNetwork) RevokeNode(nodeID string, reason string) {
-	node, ok := net.Nodes[nodeID]
-	if ok {
-		node.State = "revoked"
-		if !strings.HasSuffix(node.Pattern, ":revoked") {
-			node.Pattern = node.Pattern + ":revoked"
-		}
-		net.Nodes[nodeID] = node
+	net.mu.Lock()
+	defer net.unlock()
+	net.recordRevocationLocked(nodeID, RevocationUnspecified, reason)
+	if node, ok := net.nodes[nodeID]; ok {
+		net.revokeLocked(node, reason)
 	}
 }
 
-// PropagateRevocation revokes all nodes except the specified node.
-// PropagateRevocation revokes all nodes except the specified node.
-func (net *MeshNetwork) PropagateRevocation(nodeID string) {
-	for id, node := range net.Nodes {
-		if id != nodeID {
-			node.State = "revoked"
-			if !strings.HasSuffix(node.Pattern, ":revoked") {
-				node.Pattern = node.Pattern + ":revoked"
-			}
-			net.Nodes[id] = node
-		}
+func revoked(node QuantumMeshNode, reason string) QuantumMeshNode {
+	node.State = NodeStateRevoked
+	node.RevocationReason = reason
+	if !strings.HasSuffix(node.Pattern, ":revoked") {
+		node.Pattern = node.Pattern + ":revoked"
 	}
+	return node
 }
@@ -0,0 +1,116 @@
+// mesh_metrics.go - MeshMetrics derived from live network state
+package coherra
+
+import "time"
+
+// maxReconfigSamples bounds how many completed reconfigurations CollectMetrics averages.
+const maxReconfigSamples = 32
+
+// meshStats records the outcomes MeshNetwork needs to derive MeshMetrics.
+// It is guarded by the network lock.
+type meshStats struct {
+	validations   int
+	passed        int
+	reconfigStart time.Time
+	reconfigTimes []float64
+}
+
+// markReconfig notes a membership or link change and drops the cached path
+// variance. The reconfiguration lasts until the network is next validated with
+// ValidateAllNodes.
+func (net *MeshNetwork) markReconfig() {
+	net.pathVariance.Store(nil)
+	if net.stats.reconfigStart.IsZero() {
+		net.stats.reconfigStart = net.clock()
+	}
+}
+
+// completeReconfig closes a pending reconfiguration and records its duration.
+func (net *MeshNetwork) completeReconfig() {
+	if net.stats.reconfigStart.IsZero() {
+		return
+	}
+	d := net.clock().Sub(net.stats.reconfigStart).Seconds()
+	net.stats.reconfigStart = time.Time{}
+	net.stats.reconfigTimes = append(net.stats.reconfigTimes, d)
+	if n := len(net.stats.reconfigTimes); n > maxReconfigSamples {
+		net.stats.reconfigTimes = net.stats.reconfigTimes[n-maxReconfigSamples:]
+	}
+}
+
+// recordValidation counts the outcome of validating a node, if it was active,
+// and adds it to the stored node's Memory stamped with the network clock.
+// Outcomes are not logged one by one; they reach the store with the next
+// snapshot, written by Compact or Close.
+func (net *MeshNetwork) recordValidation(node QuantumMeshNode, err error, qre float64) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	if node.State == NodeStateActive {
+		net.stats.validations++
+		if err == nil {
+			net.stats.passed++
+		}
+	}
+	stored, ok := net.nodes[node.ID]
+	if !ok {
+		return
+	}
+	if stored.Memory == nil {
+		stored.Memory = NewGlyphMemory(DefaultGlyphMemoryCapacity)
+		net.nodes[node.ID] = stored
+	}
+	// The network owns the stored Memory; copies handed out are clones.
+	stored.Memory.Record(GlyphOutcome{Passed: err == nil, QRE: qre, Timestamp: net.clock().Unix()})
+	net.memoryDirty = true
+}
+
+// CollectMetrics derives MeshMetrics from the current network:
+//   - AvgTrustWeight and PathVariance come from the links (see AverageTrust and PathVariance).
+//   - UptimePercent is the mean share of time non-retired nodes have spent active
+//     since joining the network, replayed from their state history.
+//   - GlyphCoherence is the mean coherence of active nodes.
+//   - QREValidationRate is the share of recorded validations of active nodes that passed.
+//   - ReconfigTime is the mean number of seconds between a membership or link change
+//     and the next ValidateAllNodes, over recent reconfigurations.
+//
+// Quantities with nothing recorded yet take their ideal value.
+func (net *MeshNetwork) CollectMetrics() MeshMetrics {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	metrics := MeshMetrics{
+		AvgTrustWeight:    net.averageTrustLocked(),
+		UptimePercent:     100,
+		PathVariance:      net.pathVarianceLocked(),
+		GlyphCoherence:    1,
+		QREValidationRate: 1,
+	}
+	now := net.clock()
+	active, coherence, up, counted := 0, 0.0, 0.0, 0
+	for _, node := range net.nodes {
+		if node.State == NodeStateActive {
+			active++
+			coherence += node.Metrics.Coherence
+		}
+		if node.State != NodeStateRetired {
+			up += uptime(node, now)
+			counted++
+		}
+	}
+	if counted > 0 {
+		metrics.UptimePercent = 100 * up / float64(counted)
+	}
+	if active > 0 {
+		metrics.GlyphCoherence = coherence / float64(active)
+	}
+	if net.stats.validations > 0 {
+		metrics.QREValidationRate = float64(net.stats.passed) / float64(net.stats.validations)
+	}
+	if n := len(net.stats.reconfigTimes); n > 0 {
+		sum := 0.0
+		for _, d := range net.stats.reconfigTimes {
+			sum += d
+		}
+		metrics.ReconfigTime = sum / float64(n)
+	}
+	return metrics
+}
@@ -0,0 +1,847 @@
+package coherra
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand/v2"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mesh_test.go - MeshNetwork concurrency, topology and propagation tests
+
+func TestMeshNetworkConcurrentAccess(t *testing.T) {
+	net := NewMeshNetwork()
+	seed := testMeshNode(t)
+	net.AddNode(seed)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				node := testMeshNode(t)
+				net.AddNode(node)
+				_ = net.PropagateMetrics(seed.ID, node.ID)
+				if got, ok := net.GetNode(node.ID); ok {
+					got.CoherenceHistory = append(got.CoherenceHistory, 0.1)
+				}
+				net.Range(func(n QuantumMeshNode) bool { return n.State == "active" })
+				if i%10 == 0 {
+					net.RevokeNode(node.ID, "churn")
+					net.RemoveNode(node.ID)
+				}
+			}
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				_ = net.ValidateAllNodes()
+				_ = net.Len()
+			}
+		}()
+	}
+	wg.Wait()
+	if want := 1 + 8*(50-5); net.Len() != want {
+		t.Errorf("Len = %d, want %d", net.Len(), want)
+	}
+}
+
+func TestMeshNetworkCopiesAreIsolated(t *testing.T) {
+	net := NewMeshNetwork()
+	node := testMeshNode(t)
+	net.AddNode(node)
+	node.Metrics.Harmonics[0] = -1
+
+	got, ok := net.GetNode(node.ID)
+	if !ok {
+		t.Fatal("GetNode did not find added node")
+	}
+	if got.Metrics.Harmonics[0] == -1 {
+		t.Error("Network shares harmonics slice with caller")
+	}
+	got.CoherenceHistory[0] = -1
+	again, _ := net.GetNode(node.ID)
+	if again.CoherenceHistory[0] == -1 {
+		t.Error("GetNode returned a slice shared with the network")
+	}
+	if !net.RemoveNode(node.ID) || net.RemoveNode(node.ID) {
+		t.Error("RemoveNode should report presence exactly once")
+	}
+	if _, ok := net.GetNode(node.ID); ok {
+		t.Error("Removed node still present")
+	}
+	if err := net.PropagateMetrics(node.ID, "missing"); err != ErrNodeNotFound {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestMeshNetworkSnapshotOrdered(t *testing.T) {
+	net := NewMeshNetwork()
+	for i := 0; i < 10; i++ {
+		net.AddNode(testMeshNode(t))
+	}
+	snap := net.Snapshot()
+	for i := 1; i < len(snap); i++ {
+		if snap[i-1].ID >= snap[i].ID {
+			t.Fatalf("Snapshot not ordered by ID at %d", i)
+		}
+	}
+	visited := 0
+	net.Range(func(QuantumMeshNode) bool { visited++; return visited < 3 })
+	if visited != 3 {
+		t.Errorf("Range did not stop early: visited %d", visited)
+	}
+}
+
+func TestGossipConvergesToMeanScore(t *testing.T) {
+	net := NewMeshNetwork()
+	var ids []string
+	sum := 0.0
+	for i := 0; i < 16; i++ {
+		node := testMeshNode(t)
+		node.Metrics.ValidationScore = float64(i) / 15
+		sum += node.Metrics.ValidationScore
+		net.AddNode(node)
+		ids = append(ids, node.ID)
+	}
+	g := NewGossiper(net, GossipConfig{Fanout: 2, Rounds: 30, Rand: mrand.New(mrand.NewPCG(1, 2))})
+	update := testMeshNode(t).Metrics
+	update.Coherence = 0.42
+	update.ValidationScore = 0
+	if err := g.Publish(ids[0], update); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Publish("missing", update); err != ErrNodeNotFound {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+
+	report := g.Run()
+	if !report.Converged {
+		t.Fatalf("Gossip did not converge: %+v", report.Rounds[len(report.Rounds)-1])
+	}
+	if want := sum / 16; math.Abs(report.MeanValidationScore-want) > 1e-9 {
+		t.Errorf("Mean score = %v, want %v (pairwise averaging must preserve the sum)", report.MeanValidationScore, want)
+	}
+	last := report.Rounds[len(report.Rounds)-1]
+	if last.Coverage != 1 || last.ScoreSpread > DefaultGossipTolerance {
+		t.Errorf("Final round not converged: %+v", last)
+	}
+	if report.Rounds[0].ScoreVariance <= last.ScoreVariance {
+		t.Error("Score variance did not decrease")
+	}
+	for _, id := range ids {
+		known := g.Known(id)
+		if len(known) != 1 || known[0].Origin != ids[0] || known[0].Metrics.Coherence != 0.42 {
+			t.Fatalf("Node %s did not receive the update: %+v", id, known)
+		}
+		node, _ := net.GetNode(id)
+		if math.Abs(node.Metrics.ValidationScore-report.MeanValidationScore) > DefaultGossipTolerance {
+			t.Errorf("Node %s score %v not written back", id, node.Metrics.ValidationScore)
+		}
+	}
+}
+
+// hookSource is a rand.Source that runs hook before its first draw.
+type hookSource struct {
+	mrand.Source
+	hook func()
+}
+
+func (s *hookSource) Uint64() uint64 {
+	if s.hook != nil {
+		s.hook()
+		s.hook = nil
+	}
+	return s.Source.Uint64()
+}
+
+func TestGossipKeepsConcurrentScoreChanges(t *testing.T) {
+	net := NewMeshNetwork()
+	a, b := testMeshNode(t), testMeshNode(t)
+	a.Metrics.ValidationScore, b.Metrics.ValidationScore = 0, 1
+	net.AddNode(a)
+	net.AddNode(b)
+	// a's score rises while the run is in progress.
+	src := &hookSource{Source: mrand.NewPCG(3, 4), hook: func() {
+		net.update(a.ID, func(node *QuantumMeshNode) { node.Metrics.ValidationScore += 0.5 })
+	}}
+	g := NewGossiper(net, GossipConfig{Fanout: 1, Rounds: 1, Rand: mrand.New(src)})
+	update := b.Metrics
+	update.Coherence = 0.42
+	if err := g.Publish(b.ID, update); err != nil {
+		t.Fatal(err)
+	}
+	report := g.Run()
+
+	gotA, _ := net.GetNode(a.ID)
+	gotB, _ := net.GetNode(b.ID)
+	if math.Abs(gotA.Metrics.ValidationScore-1) > 1e-9 || math.Abs(gotB.Metrics.ValidationScore-0.5) > 1e-9 {
+		t.Errorf("Scores = %v, %v; want 1 (0.5 averaged plus the concurrent 0.5) and 0.5",
+			gotA.Metrics.ValidationScore, gotB.Metrics.ValidationScore)
+	}
+	hist := gotA.Metrics.CoherenceHistory
+	if report.Delivered != 1 || len(hist) == 0 || hist[len(hist)-1] != 0.42 {
+		t.Errorf("Remote update did not reach a: delivered %d, history %v", report.Delivered, hist)
+	}
+	if gotA.Metrics.Coherence == 0.42 || len(gotB.Metrics.CoherenceHistory) != len(b.Metrics.CoherenceHistory) {
+		t.Error("Update should only be appended to the receiving node's history")
+	}
+}
+
+func TestGossipCountsWriteErrors(t *testing.T) {
+	net := NewMeshNetwork()
+	a, b := testMeshNode(t), testMeshNode(t)
+	net.AddNode(a)
+	net.AddNode(b)
+	// a leaves the network while the run is in progress.
+	src := &hookSource{Source: mrand.NewPCG(3, 4), hook: func() { net.RemoveNode(a.ID) }}
+	report := NewGossiper(net, GossipConfig{Fanout: 1, Rounds: 1, Rand: mrand.New(src)}).Run()
+	if report.WriteErrors != 1 {
+		t.Errorf("WriteErrors = %d, want 1", report.WriteErrors)
+	}
+}
+
+func TestGossipSkipsRevokedNodes(t *testing.T) {
+	net := NewMeshNetwork()
+	a, b, c := testMeshNode(t), testMeshNode(t), testMeshNode(t)
+	a.Metrics.ValidationScore, b.Metrics.ValidationScore, c.Metrics.ValidationScore = 0.2, 0.8, 0.0
+	for _, n := range []QuantumMeshNode{a, b, c} {
+		net.AddNode(n)
+	}
+	net.RevokeNode(c.ID, "compromised")
+	report := NewGossiper(net, GossipConfig{Rand: mrand.New(mrand.NewPCG(3, 4))}).Run()
+	if !report.Converged || math.Abs(report.MeanValidationScore-0.5) > 1e-9 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if got, _ := net.GetNode(c.ID); got.Metrics.ValidationScore != 0 {
+		t.Error("Revoked node took part in gossip")
+	}
+}
+
+func TestMeshTopologyPaths(t *testing.T) {
+	net := NewMeshNetwork()
+	var n [4]QuantumMeshNode
+	for i := range n {
+		n[i] = testMeshNode(t)
+		net.AddNode(n[i])
+	}
+	if net.AverageTrust() != 1 || net.PathVariance() != 0 {
+		t.Error("Network without links should report full trust and zero variance")
+	}
+	// Line a-b-c plus a slow direct link a-c; d stays isolated.
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(net.Connect(n[0].ID, n[1].ID, 0.9, 10*time.Millisecond))
+	must(net.Connect(n[1].ID, n[2].ID, 0.7, 10*time.Millisecond))
+	must(net.Connect(n[0].ID, n[2].ID, 0.2, 50*time.Millisecond))
+	if err := net.Connect(n[0].ID, n[0].ID, 0.5, 0); err != ErrInvalidEdge {
+		t.Errorf("Self link: expected ErrInvalidEdge, got %v", err)
+	}
+	if err := net.Connect(n[0].ID, n[1].ID, 1.5, 0); err != ErrInvalidEdge {
+		t.Errorf("Trust > 1: expected ErrInvalidEdge, got %v", err)
+	}
+	if err := net.Connect(n[0].ID, "missing", 0.5, 0); err != ErrNodeNotFound {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+
+	path, latency, err := net.ShortestPath(n[0].ID, n[2].ID)
+	if err != nil || len(path) != 3 || path[1] != n[1].ID || latency != 20*time.Millisecond {
+		t.Errorf("ShortestPath = %v, %v, %v", path, latency, err)
+	}
+	if _, _, err := net.ShortestPath(n[0].ID, n[3].ID); err != ErrNoPath {
+		t.Errorf("Expected ErrNoPath, got %v", err)
+	}
+	if got := net.Neighbors(n[2].ID); len(got) != 2 {
+		t.Errorf("Neighbors = %v", got)
+	}
+	if got := net.AverageTrust(); math.Abs(got-0.6) > 1e-9 {
+		t.Errorf("AverageTrust = %v, want 0.6", got)
+	}
+	// Pairs: a-b 1 hop, b-c 1 hop, a-c 2 hops via b.
+	if got := net.PathVariance(); math.Abs(got-2.0/9) > 1e-9 {
+		t.Errorf("PathVariance = %v, want 2/9", got)
+	}
+	metrics := net.CollectMetrics()
+	if metrics.AvgTrustWeight != net.AverageTrust() || metrics.PathVariance != net.PathVariance() {
+		t.Error("CollectMetrics did not use the network links")
+	}
+	// The cached variance is dropped when the links change.
+	if err := net.Connect(n[0].ID, n[2].ID, 0.5, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := net.PathVariance(); got != 0 {
+		t.Errorf("PathVariance after closing the triangle = %v, want 0", got)
+	}
+
+	net.RemoveNode(n[1].ID)
+	if len(net.Edges()) != 1 || len(net.Neighbors(n[1].ID)) != 0 {
+		t.Errorf("RemoveNode left links behind: %+v", net.Edges())
+	}
+	if !net.Disconnect(n[2].ID, n[0].ID) || net.Disconnect(n[0].ID, n[2].ID) {
+		t.Error("Disconnect should report the link exactly once")
+	}
+}
+
+func TestGossipFollowsLinks(t *testing.T) {
+	net := NewMeshNetwork()
+	a, b, c := testMeshNode(t), testMeshNode(t), testMeshNode(t)
+	for _, n := range []QuantumMeshNode{a, b, c} {
+		net.AddNode(n)
+	}
+	if err := net.Connect(a.ID, b.ID, 1, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	g := NewGossiper(net, GossipConfig{Rounds: 5, Rand: mrand.New(mrand.NewPCG(5, 6))})
+	if err := g.Publish(a.ID, a.Metrics); err != nil {
+		t.Fatal(err)
+	}
+	report := g.Run()
+	if report.Converged || len(g.Known(b.ID)) != 1 || len(g.Known(c.ID)) != 0 {
+		t.Error("Gossip reached a node with no links")
+	}
+}
+
+func TestCollectMetricsFromNetworkState(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	net := Options{Clock: func() time.Time { return now }}.NewMeshNetwork()
+	ideal := MeshMetrics{AvgTrustWeight: 1, UptimePercent: 100, GlyphCoherence: 1, QREValidationRate: 1}
+	if got := net.CollectMetrics(); got != ideal {
+		t.Errorf("Empty network metrics = %+v, want %+v", got, ideal)
+	}
+
+	good, weak, gone := testMeshNode(t), testMeshNode(t), testMeshNode(t)
+	weak.Metrics.Coherence = MinCoherence / 2
+	for _, n := range []QuantumMeshNode{good, weak, gone} {
+		// Uptime runs from the join time, not the caller-supplied timestamp.
+		n.Timestamp = now.Unix() - 3600
+		net.AddNode(n)
+	}
+	now = now.Add(6 * time.Second)
+	net.RevokeNode(gone.ID, "test")
+	now = now.Add(4 * time.Second)
+	results := net.ValidateAllNodes()
+	if results[good.ID] != nil || results[weak.ID] == nil {
+		t.Fatalf("Unexpected validation results: %v", results)
+	}
+
+	got := net.CollectMetrics()
+	// The revoked node was active for 6 of its 10 seconds.
+	if want := 100 * (1 + 1 + 0.6) / 3; math.Abs(got.UptimePercent-want) > 1e-9 {
+		t.Errorf("UptimePercent = %v, want %v", got.UptimePercent, want)
+	}
+	if want := (good.Metrics.Coherence + weak.Metrics.Coherence) / 2; math.Abs(got.GlyphCoherence-want) > 1e-9 {
+		t.Errorf("GlyphCoherence = %v, want %v", got.GlyphCoherence, want)
+	}
+	if got.QREValidationRate != 0.5 {
+		t.Errorf("QREValidationRate = %v, want 0.5 (revoked nodes are not counted)", got.QREValidationRate)
+	}
+	if got.ReconfigTime != 10 {
+		t.Errorf("ReconfigTime = %v, want 10", got.ReconfigTime)
+	}
+	if joined, _ := net.GetNode(good.ID); joined.JoinedAt != now.Unix()-10 {
+		t.Errorf("JoinedAt = %d, want %d", joined.JoinedAt, now.Unix()-10)
+	}
+	if CalculateMeshScore(got) == CalculateMeshScore(ideal) {
+		t.Error("Mesh score did not reflect network state")
+	}
+}
+
+func TestPropagateRevocationScoped(t *testing.T) {
+	net := NewMeshNetwork()
+	var n [5]QuantumMeshNode
+	for i := range n {
+		n[i] = testMeshNode(t)
+		net.AddNode(n[i])
+	}
+	// n0 -0.9- n1 -1.0- n2 -1.0- n3, and n0 -0.3- n4.
+	links := []struct {
+		a, b  int
+		trust float64
+	}{{0, 1, 0.9}, {1, 2, 1.0}, {2, 3, 1.0}, {0, 4, 0.3}}
+	for _, l := range links {
+		if err := net.Connect(n[l.a].ID, n[l.b].ID, l.trust, time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy := RevocationPolicy{MaxDepth: 2, TrustDecay: 0.9, Threshold: 0.5}
+	report, err := net.PropagateRevocation(n[0].ID, "key compromise", policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Suspicion: n1 = 0.81, n2 = 0.729, n4 = 0.27; n3 is beyond MaxDepth.
+	var revokedIDs, sparedIDs []string
+	for _, e := range report.Revoked {
+		revokedIDs = append(revokedIDs, e.NodeID)
+	}
+	for _, e := range report.Spared {
+		sparedIDs = append(sparedIDs, e.NodeID)
+	}
+	if len(revokedIDs) != 3 || revokedIDs[0] != n[0].ID || revokedIDs[1] != n[1].ID || revokedIDs[2] != n[2].ID {
+		t.Errorf("Revoked = %v", revokedIDs)
+	}
+	if len(sparedIDs) != 1 || sparedIDs[0] != n[4].ID {
+		t.Errorf("Spared = %v", sparedIDs)
+	}
+	if report.Revoked[2].Via != n[1].ID || math.Abs(report.Revoked[2].Suspicion-0.729) > 1e-9 {
+		t.Errorf("Unexpected entry for depth 2: %+v", report.Revoked[2])
+	}
+
+	origin, _ := net.GetNode(n[0].ID)
+	if origin.State != "revoked" || origin.RevocationReason != "key compromise" {
+		t.Errorf("Origin not revoked with reason: %q %q", origin.State, origin.RevocationReason)
+	}
+	for _, i := range []int{3, 4} {
+		if got, _ := net.GetNode(n[i].ID); got.State != "active" {
+			t.Errorf("Node %d should stay active", i)
+		}
+	}
+	if _, err := net.PropagateRevocation("missing", "x", policy); err != ErrNodeNotFound {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+
+	net.RevokeNode(n[3].ID, "operator request")
+	if got, _ := net.GetNode(n[3].ID); got.RevocationReason != "operator request" {
+		t.Errorf("RevokeNode discarded reason: %q", got.RevocationReason)
+	}
+}
+
+func TestPropagateRevocationDiamond(t *testing.T) {
+	net := NewMeshNetwork()
+	names := []string{"o", "a", "b", "c", "d"}
+	ids := make(map[string]string)
+	for _, name := range names {
+		node := testMeshNode(t)
+		net.AddNode(node)
+		ids[name] = node.ID
+	}
+	// o reaches c through a and through b, and b also vouches for a. Raising a
+	// via b must not let a spread its raised suspicion in the same round.
+	links := []struct {
+		a, b  string
+		trust float64
+	}{{"o", "a", 0.5}, {"o", "b", 1.0}, {"a", "b", 0.8}, {"a", "c", 1.0}, {"b", "c", 0.4}, {"c", "d", 1.0}}
+	for _, l := range links {
+		if err := net.Connect(ids[l.a], ids[l.b], l.trust, time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy := RevocationPolicy{MaxDepth: 2, TrustDecay: 1, Threshold: 0.3}
+	report, err := net.PropagateRevocation(ids["o"], "key compromise", policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]RevocationEntry{
+		ids["o"]: {Depth: 0, Suspicion: 1},
+		ids["b"]: {Depth: 1, Via: ids["o"], Suspicion: 1},
+		ids["a"]: {Depth: 2, Via: ids["b"], Suspicion: 0.8},
+		ids["c"]: {Depth: 2, Via: ids["a"], Suspicion: 0.5},
+	}
+	if len(report.Revoked) != len(want) || len(report.Spared) != 0 {
+		t.Fatalf("Revoked = %+v, spared = %+v", report.Revoked, report.Spared)
+	}
+	for _, e := range report.Revoked {
+		w, ok := want[e.NodeID]
+		if !ok || e.Depth != w.Depth || e.Via != w.Via || math.Abs(e.Suspicion-w.Suspicion) > 1e-9 {
+			t.Errorf("Entry %+v, want %+v", e, w)
+		}
+	}
+	if got, _ := net.GetNode(ids["d"]); got.State != NodeStateActive {
+		t.Error("Node beyond MaxDepth was revoked")
+	}
+}
+
+func TestRevocationListSignSaveLoad(t *testing.T) {
+	issuer := testMeshNode(t)
+	signer, err := NewEd25519Signer(issuer, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewRevocationList(issuer.ID)
+	l.Revoke(RevokedNode{NodeID: "node-b", Code: RevocationKeyCompromise, RevokedAt: 100})
+	l.Revoke(RevokedNode{NodeID: "node-a", RevokedAt: 200})
+	if l.Revoke(RevokedNode{NodeID: "node-b", Code: RevocationSuperseded}) {
+		t.Error("Revoke replaced an existing entry")
+	}
+	if err := l.Verify(signer.Verifier()); err != ErrRevocationListUnsigned {
+		t.Errorf("Expected ErrRevocationListUnsigned, got %v", err)
+	}
+	if err := l.Sign(signer, time.Unix(300, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if l.Version != 1 || l.IssuedAt != 300 || l.Entries[0].NodeID != "node-a" || l.Entries[0].Code != RevocationUnspecified {
+		t.Errorf("Unexpected signed list: %+v", l)
+	}
+
+	path := filepath.Join(t.TempDir(), "crl.json")
+	if err := SaveRevocationList(path, l); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRevocationList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Verify(signer.Verifier()); err != nil {
+		t.Fatalf("Loaded list does not verify: %v", err)
+	}
+	var revokedErr *RevokedNodeError
+	if err := loaded.Check("node-b"); !errors.As(err, &revokedErr) || revokedErr.Code != RevocationKeyCompromise {
+		t.Errorf("Check(node-b) = %v", err)
+	}
+	if err := loaded.Check("node-c"); err != nil {
+		t.Errorf("Check(node-c) = %v", err)
+	}
+
+	loaded.Entries[0].Code = RevocationSuperseded
+	if err := loaded.Verify(signer.Verifier()); err != ErrInvalidSignature {
+		t.Errorf("Tampered list: expected ErrInvalidSignature, got %v", err)
+	}
+	if err := l.Verify(testSigner(t).Verifier()); err != ErrSignatureNodeMismatch {
+		t.Errorf("Wrong issuer: expected ErrSignatureNodeMismatch, got %v", err)
+	}
+	if _, err := ParseRevocationList([]byte(`{"Entries":[{"NodeID":"b"},{"NodeID":"a"}]}`)); err != ErrRevocationListMalformed {
+		t.Errorf("Unsorted entries: expected ErrRevocationListMalformed, got %v", err)
+	}
+}
+
+func TestMeshNetworkEnforcesRevocationList(t *testing.T) {
+	issuer, target, bystander := testMeshNode(t), testMeshNode(t), testMeshNode(t)
+	signer, err := NewEd25519Signer(issuer, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The issuing network revokes a node and signs its list.
+	netA := NewMeshNetwork()
+	netA.SetRevocationList(NewRevocationList(issuer.ID))
+	netA.AddNode(target)
+	netA.RevokeNode(target.ID, "leaked key")
+	crl, err := netA.SignRevocationList(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := crl.Lookup(target.ID); !ok || entry.Detail != "leaked key" {
+		t.Fatalf("RevokeNode not recorded in list: %+v", crl.Entries)
+	}
+
+	// A restarted peer merges the list and keeps the node revoked even if it is re-added.
+	netB := NewMeshNetwork()
+	netB.AddNode(target)
+	netB.AddNode(bystander)
+	if _, err := netB.MergeRevocationList(crl, testSigner(t).Verifier()); err == nil {
+		t.Error("Merged a list with an untrusted verifier")
+	}
+	if netB.RevocationList() != nil {
+		t.Error("A rejected list turned on revocation recording")
+	}
+	added, err := netB.MergeRevocationList(crl, signer.Verifier())
+	if err != nil || added != 1 {
+		t.Fatalf("MergeRevocationList = %d, %v", added, err)
+	}
+	netB.AddNode(target)
+	if got, _ := netB.GetNode(target.ID); got.State != "revoked" {
+		t.Error("Re-added node is not revoked")
+	}
+	var revokedErr *RevokedNodeError
+	if err := netB.ValidateAllNodes()[target.ID]; !errors.As(err, &revokedErr) {
+		t.Errorf("Expected RevokedNodeError, got %v", err)
+	}
+	if err := netB.PropagateMetrics(bystander.ID, target.ID); !errors.As(err, &revokedErr) {
+		t.Errorf("Expected RevokedNodeError, got %v", err)
+	}
+	// A caller's unrevoked copy still fails against the list.
+	glyph := netB.defaultGlyph(target)
+	if err := ValidateMeshNode(target, DefaultMeshScore, glyph, DefaultMeshScore, nil, crl); !errors.As(err, &revokedErr) {
+		t.Errorf("ValidateMeshNode: expected RevokedNodeError, got %v", err)
+	}
+	if err := netB.ValidateNode(target, DefaultMeshScore, glyph, DefaultMeshScore); !errors.As(err, &revokedErr) {
+		t.Errorf("ValidateNode: expected RevokedNodeError, got %v", err)
+	}
+	if err := netB.ValidateNode(bystander, DefaultMeshScore, glyph, DefaultMeshScore); err != nil {
+		t.Errorf("ValidateNode rejected an unlisted node: %v", err)
+	}
+	if got, _ := netB.GetNode(bystander.ID); got.State != "active" {
+		t.Error("Bystander revoked")
+	}
+
+	// Once a newer version is merged, the older list cannot be replayed.
+	netA.RevokeNode(bystander.ID, "later")
+	newer, err := netA.SignRevocationList(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := netB.MergeRevocationList(newer, signer.Verifier()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := netB.MergeRevocationList(crl, signer.Verifier()); err != ErrRevocationListStale {
+		t.Errorf("Expected ErrRevocationListStale, got %v", err)
+	}
+}
+
+// testSigner returns a signer for a fresh node.
+func testSigner(t *testing.T) *Ed25519Signer {
+	t.Helper()
+	s, err := NewEd25519Signer(testMeshNode(t), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNodeLifecycleTransitions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	net := Options{Clock: func() time.Time { return now }}.NewMeshNetwork()
+	var seen []StateTransition
+	net.OnTransition(func(nodeID string, tr StateTransition) {
+		// Hooks run unlocked and may read the network.
+		if got, _ := net.GetNode(nodeID); got.State != tr.To {
+			t.Errorf("Hook saw state %q, want %q", got.State, tr.To)
+		}
+		seen = append(seen, tr)
+	})
+
+	node := testMeshNode(t)
+	node.State = NodeStatePending
+	net.AddNode(node)
+	if err := net.Transition(node.ID, NodeStateActive, "admitted"); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if err := net.Quarantine(node.ID, "coherence drop"); err != nil {
+		t.Fatal(err)
+	}
+	var transitionErr *StateTransitionError
+	if err := net.Transition(node.ID, NodeStatePending, ""); !errors.As(err, &transitionErr) || transitionErr.From != NodeStateQuarantined {
+		t.Errorf("Expected StateTransitionError, got %v", err)
+	}
+	if err := net.Transition(node.ID, NodeStateActive, "recovered"); err != nil {
+		t.Fatal(err)
+	}
+	net.RevokeNode(node.ID, "compromised")
+	if err := net.Transition(node.ID, NodeStateActive, ""); !errors.As(err, &transitionErr) {
+		t.Errorf("Revoked must be terminal, got %v", err)
+	}
+	for _, to := range []NodeState{NodeStateActive, NodeStateRevoked} {
+		if err := net.Transition("missing", to, ""); err != ErrNodeNotFound {
+			t.Errorf("Transition to %s: expected ErrNodeNotFound, got %v", to, err)
+		}
+	}
+
+	got, _ := net.GetNode(node.ID)
+	want := []NodeState{NodeStateActive, NodeStateQuarantined, NodeStateActive, NodeStateRevoked}
+	if len(got.StateHistory) != len(want) || len(seen) != len(want) {
+		t.Fatalf("History = %+v, hooks saw %+v", got.StateHistory, seen)
+	}
+	for i, s := range want {
+		if got.StateHistory[i].To != s || seen[i] != got.StateHistory[i] {
+			t.Errorf("Transition %d = %+v, want to %s", i, got.StateHistory[i], s)
+		}
+	}
+	if got.StateHistory[1].At != now.Unix() || got.StateHistory[1].Reason != "coherence drop" {
+		t.Errorf("Quarantine not recorded with clock and reason: %+v", got.StateHistory[1])
+	}
+}
+
+func TestStateHistoryIsCapped(t *testing.T) {
+	net := NewMeshNetwork()
+	node := testMeshNode(t)
+	net.AddNode(node)
+	for i := 0; i < MaxStateHistory; i++ {
+		if err := net.Quarantine(node.ID, "drift"); err != nil {
+			t.Fatal(err)
+		}
+		if err := net.Transition(node.ID, NodeStateActive, "recovered"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, _ := net.GetNode(node.ID)
+	if len(got.StateHistory) != MaxStateHistory || got.StateHistory[len(got.StateHistory)-1].To != NodeStateActive {
+		t.Errorf("History length = %d, want %d ending active", len(got.StateHistory), MaxStateHistory)
+	}
+}
+
+func TestReAddKeepsLifecycle(t *testing.T) {
+	net := NewMeshNetwork()
+	node := testMeshNode(t)
+	net.AddNode(node)
+	if err := net.Quarantine(node.ID, "drift"); err != nil {
+		t.Fatal(err)
+	}
+	// Re-adding or merging an active copy must not release the node.
+	net.AddNode(node)
+	if err := net.mergeRemoteNode(node); err != ErrNodeNotActive {
+		t.Errorf("Merging a quarantined node: expected ErrNodeNotActive, got %v", err)
+	}
+	got, _ := net.GetNode(node.ID)
+	if got.State != NodeStateQuarantined || len(got.StateHistory) != 2 {
+		t.Errorf("Re-added node = %q with history %+v", got.State, got.StateHistory)
+	}
+
+	// New nodes join pending and reach their state through a recorded transition.
+	joined := got.StateHistory[0]
+	if joined.From != NodeStatePending || joined.To != NodeStateActive {
+		t.Errorf("Join transition = %+v", joined)
+	}
+	retired := testMeshNode(t)
+	retired.State = NodeStateRetired
+	net.AddNode(retired)
+	if got, _ := net.GetNode(retired.ID); got.State != NodeStateRetired || len(got.StateHistory) != 1 {
+		t.Errorf("Retired node = %q with history %+v", got.State, got.StateHistory)
+	}
+	odd, held := testMeshNode(t), testMeshNode(t)
+	odd.State = "rogue"
+	held.State = NodeStateSuspended
+	net.AddNode(odd)
+	net.AddNode(held)
+	for _, id := range []string{odd.ID, held.ID} {
+		if got, _ := net.GetNode(id); got.State != NodeStatePending || len(got.StateHistory) != 0 {
+			t.Errorf("Node outside pending's reach stored as %q, want pending", got.State)
+		}
+	}
+}
+
+func TestPropagateMetricsSkipsIsolatedNodes(t *testing.T) {
+	net := NewMeshNetwork()
+	a, b := testMeshNode(t), testMeshNode(t)
+	a.Metrics.ValidationScore, b.Metrics.ValidationScore = 0, 1
+	net.AddNode(a)
+	net.AddNode(b)
+	if err := net.Quarantine(a.ID, "drift"); err != nil {
+		t.Fatal(err)
+	}
+	if err := net.PropagateMetrics(a.ID, b.ID); err != ErrNodeNotActive {
+		t.Errorf("Quarantined source: expected ErrNodeNotActive, got %v", err)
+	}
+	if err := net.PropagateMetrics(b.ID, a.ID); err != ErrNodeNotActive {
+		t.Errorf("Quarantined target: expected ErrNodeNotActive, got %v", err)
+	}
+	net.RevokeNode(a.ID, "compromised")
+	if err := net.PropagateMetrics(a.ID, b.ID); err != ErrNodeNotActive {
+		t.Errorf("Revoked source: expected ErrNodeNotActive, got %v", err)
+	}
+	if got, _ := net.GetNode(b.ID); got.Metrics.ValidationScore != 1 {
+		t.Errorf("Isolated node's score was averaged in: %v", got.Metrics.ValidationScore)
+	}
+}
+
+func TestQuarantineFailingNodes(t *testing.T) {
+	net := NewMeshNetwork()
+	good, weak := testMeshNode(t), testMeshNode(t)
+	weak.Metrics.Coherence = MinCoherence / 2
+	net.AddNode(good)
+	net.AddNode(weak)
+
+	quarantined := net.QuarantineFailingNodes()
+	if len(quarantined) != 1 || quarantined[weak.ID] == nil {
+		t.Fatalf("Quarantined = %v", quarantined)
+	}
+	if got, _ := net.GetNode(weak.ID); got.State != NodeStateQuarantined {
+		t.Errorf("Weak node state = %q", got.State)
+	}
+	if got, _ := net.GetNode(good.ID); got.State != NodeStateActive {
+		t.Errorf("Good node state = %q", got.State)
+	}
+	// Quarantined nodes are not re-quarantined and can be released.
+	if again := net.QuarantineFailingNodes(); len(again) != 0 {
+		t.Errorf("Second pass quarantined %v", again)
+	}
+	if err := net.Transition(weak.ID, NodeStateActive, "operator release"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPatternRegistryMatchesValidatePattern(t *testing.T) {
+	r := NewPatternRegistry(0)
+	history := []string{"Harmonic-Pattern-1"}
+	if err := r.Register(history[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"harmonic-pattern-1", "short", "harmonic-pattern-2"} {
+		want := ValidatePattern(p, history)
+		got := r.Register(p)
+		if (want == nil) != (got == nil) || (want != nil && want.Error() != got.Error()) {
+			t.Errorf("Register(%q) = %v, ValidatePattern = %v", p, got, want)
+		}
+	}
+	if !r.Contains("HARMONIC-PATTERN-2") || r.Len() != 2 {
+		t.Error("Registry lookup is not case-insensitive")
+	}
+}
+
+func TestPatternRegistryConcurrentRegister(t *testing.T) {
+	r := NewPatternRegistry(0)
+	r.EnableBloomFilter(1024, 0.01)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wins := 0
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r.Register("contested-pattern") == nil {
+				mu.Lock()
+				wins++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Errorf("%d concurrent registrations succeeded, want 1", wins)
+	}
+}
+
+func TestPatternRegistryTTLAndPersistence(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	opts := Options{Clock: func() time.Time { return now }}
+	r := opts.NewPatternRegistry(time.Hour)
+	r.EnableBloomFilter(16, 0.01)
+	for i := 0; i < 200; i++ {
+		if err := r.Register(fmt.Sprintf("bulk-pattern-%03d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		if !r.Contains(fmt.Sprintf("bulk-pattern-%03d", i)) {
+			t.Fatalf("Bloom filter produced a false negative for pattern %d", i)
+		}
+	}
+
+	now = now.Add(30 * time.Minute)
+	if err := r.Register("late-pattern"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "patterns.json")
+	if err := r.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(45 * time.Minute)
+	if r.Contains("bulk-pattern-000") || r.Len() != 1 {
+		t.Error("Expired patterns still visible")
+	}
+	if err := r.Register("bulk-pattern-000"); err != nil {
+		t.Errorf("Expired pattern could not be registered again: %v", err)
+	}
+	if removed := r.Expire(); removed != 199 {
+		t.Errorf("Expire removed %d, want 199", removed)
+	}
+
+	loaded := opts.NewPatternRegistry(time.Hour)
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Contains("late-pattern") || loaded.Contains("bulk-pattern-001") {
+		t.Error("Loaded registry lost registration times")
+	}
+}
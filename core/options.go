@@ -0,0 +1,57 @@
+// options.go - Injectable randomness and clock for reproducible QALX runs
+package coherra
+
+import (
+	"io"
+	"time"
+)
+
+// Options carries the random reader and clock used by QALX constructors.
+// The zero value behaves like DefaultOptions.
+type Options struct {
+	// Rand supplies IDs, signatures and key secrets. Defaults to the default entropy pool.
+	Rand io.Reader
+	// Clock stamps objects that arrive without a timestamp. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// DefaultOptions returns options backed by the default entropy pool and the wall clock.
+func DefaultOptions() Options {
+	return Options{Rand: defaultEntropyPool, Clock: time.Now}
+}
+
+// NewDeterministicOptions returns options whose randomness and clock are fixed by
+// seed and now, so identical calls produce identical output. Use only in tests.
+func NewDeterministicOptions(seed [32]byte, now time.Time) Options {
+	return Options{
+		Rand:  NewDeterministicEntropySource(seed),
+		Clock: func() time.Time { return now },
+	}
+}
+
+func (o Options) random() io.Reader {
+	if o.Rand == nil {
+		return defaultEntropyPool
+	}
+	return o.Rand
+}
+
+func (o Options) now() time.Time {
+	if o.Clock == nil {
+		return time.Now()
+	}
+	return o.Clock()
+}
+
+// timestamp returns ts, or the current clock time in Unix seconds when ts is unset.
+func (o Options) timestamp(ts int64) int64 {
+	if ts == 0 {
+		return o.now().Unix()
+	}
+	return ts
+}
+
+// GenerateSecureKey is QALXGenerateSecureKey drawing its secret from the options' random reader.
+func (o Options) GenerateSecureKey(metrics QuantumMetrics, glyph LyraGlyph, meshScore float64) ([]byte, error) {
+	return QALXGenerateSecureKeyFrom(o.random(), metrics, glyph, meshScore)
+}
@@ -0,0 +1,250 @@
+// pattern_registry.go - Constant-time pattern history with expiry and persistence
+package coherra
+
+import (
+	"encoding/json"
+	"hash/maphash"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PatternRecord is one registered pattern. Pattern keeps the case it was registered with.
+type PatternRecord struct {
+	Pattern      string
+	RegisteredAt time.Time
+}
+
+// PatternRegistry is a concurrency-safe pattern history. It applies the same
+// rules as ValidatePattern (case-insensitive uniqueness and MinPatternLength)
+// with map lookups instead of a scan, and optionally forgets patterns after a TTL.
+type PatternRegistry struct {
+	mu      sync.Mutex
+	entries map[string]PatternRecord
+	ttl     time.Duration
+	now     func() time.Time
+	bloom   *bloomFilter
+}
+
+// NewPatternRegistry creates a registry whose patterns expire after ttl; zero keeps them forever.
+func NewPatternRegistry(ttl time.Duration) *PatternRegistry {
+	return DefaultOptions().NewPatternRegistry(ttl)
+}
+
+// NewPatternRegistry creates a registry that ages patterns with the options' clock.
+func (o Options) NewPatternRegistry(ttl time.Duration) *PatternRegistry {
+	return &PatternRegistry{entries: make(map[string]PatternRecord), ttl: ttl, now: o.now}
+}
+
+// EnableBloomFilter puts a Bloom filter sized for expected patterns at the given
+// false-positive rate in front of the map, so lookups of unseen patterns in very
+// large histories usually skip the map entirely.
+func (r *PatternRegistry) EnableBloomFilter(expected int, falsePositiveRate float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bloom = newBloomFilter(expected, falsePositiveRate)
+	for key := range r.entries {
+		r.bloom.add(key)
+	}
+}
+
+// expired reports whether rec is past the registry's TTL at now.
+func (r *PatternRegistry) expired(rec PatternRecord, now time.Time) bool {
+	return r.ttl > 0 && now.Sub(rec.RegisteredAt) >= r.ttl
+}
+
+// lookupLocked returns the live record for a normalized pattern.
+func (r *PatternRegistry) lookupLocked(key string, now time.Time) (PatternRecord, bool) {
+	if r.bloom != nil && !r.bloom.mayContain(key) {
+		return PatternRecord{}, false
+	}
+	rec, ok := r.entries[key]
+	if !ok || r.expired(rec, now) {
+		return PatternRecord{}, false
+	}
+	return rec, true
+}
+
+// Register atomically checks pattern and records it. It returns the same
+// *PatternValidationError values as ValidatePattern, so two callers racing to
+// register one pattern cannot both succeed.
+func (r *PatternRegistry) Register(pattern string) error {
+	key := strings.ToLower(pattern)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if _, ok := r.lookupLocked(key, now); ok {
+		return &PatternValidationError{Reason: "Pattern is not unique in mesh history"}
+	}
+	if len(key) < MinPatternLength {
+		return &PatternValidationError{Reason: "Pattern entropy too low"}
+	}
+	// Drop the monotonic reading so records compare equal after a JSON round trip.
+	r.insertLocked(PatternRecord{Pattern: pattern, RegisteredAt: now.Round(0).UTC()})
+	return nil
+}
+
+func (r *PatternRegistry) insertLocked(rec PatternRecord) {
+	key := strings.ToLower(rec.Pattern)
+	r.entries[key] = rec
+	if r.bloom != nil {
+		r.bloom.add(key)
+	}
+}
+
+// get returns the live record for pattern.
+func (r *PatternRegistry) get(pattern string) (PatternRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookupLocked(strings.ToLower(pattern), r.now())
+}
+
+// Contains reports whether pattern is registered and not expired.
+func (r *PatternRegistry) Contains(pattern string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.lookupLocked(strings.ToLower(pattern), r.now())
+	return ok
+}
+
+// Len returns the number of live patterns.
+func (r *PatternRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now, n := r.now(), 0
+	for _, rec := range r.entries {
+		if !r.expired(rec, now) {
+			n++
+		}
+	}
+	return n
+}
+
+// Expire removes expired patterns and returns how many were removed. The Bloom
+// filter, which cannot forget, is rebuilt from the remaining patterns.
+func (r *PatternRegistry) Expire() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now, removed := r.now(), 0
+	for key, rec := range r.entries {
+		if r.expired(rec, now) {
+			delete(r.entries, key)
+			removed++
+		}
+	}
+	if removed > 0 && r.bloom != nil {
+		r.bloom = newBloomFilter(r.bloom.expected, r.bloom.rate)
+		for key := range r.entries {
+			r.bloom.add(key)
+		}
+	}
+	return removed
+}
+
+// Patterns returns the live patterns, oldest first.
+func (r *PatternRegistry) Patterns() []PatternRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	records := make([]PatternRecord, 0, len(r.entries))
+	for _, rec := range r.entries {
+		if !r.expired(rec, now) {
+			records = append(records, rec)
+		}
+	}
+	slices.SortFunc(records, func(a, b PatternRecord) int {
+		if c := a.RegisteredAt.Compare(b.RegisteredAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Pattern, b.Pattern)
+	})
+	return records
+}
+
+// Save writes the live patterns to path atomically.
+func (r *PatternRegistry) Save(path string) error {
+	data, err := json.MarshalIndent(r.Patterns(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
+
+// Load adds the patterns saved at path, keeping their registration times.
+// Patterns already present are left unchanged.
+func (r *PatternRegistry) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var records []PatternRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for _, rec := range records {
+		if _, ok := r.lookupLocked(strings.ToLower(rec.Pattern), now); !ok {
+			r.insertLocked(rec)
+		}
+	}
+	return nil
+}
+
+// bloomFilter is a fixed-size Bloom filter using double hashing.
+type bloomFilter struct {
+	bits     []uint64
+	k        int
+	seeds    [2]maphash.Seed
+	expected int
+	rate     float64
+}
+
+func newBloomFilter(expected int, rate float64) *bloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	if rate <= 0 || rate >= 1 {
+		rate = 0.01
+	}
+	m := math.Ceil(-float64(expected) * math.Log(rate) / (math.Ln2 * math.Ln2))
+	k := max(1, int(math.Round(m/float64(expected)*math.Ln2)))
+	return &bloomFilter{
+		bits:     make([]uint64, (int(m)+63)/64),
+		k:        k,
+		seeds:    [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		expected: expected,
+		rate:     rate,
+	}
+}
+
+func (b *bloomFilter) positions(key string, fn func(bit uint64) bool) {
+	n := uint64(len(b.bits) * 64)
+	h1 := maphash.String(b.seeds[0], key)
+	h2 := maphash.String(b.seeds[1], key) | 1
+	for i := 0; i < b.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % n) {
+			return
+		}
+	}
+}
+
+func (b *bloomFilter) add(key string) {
+	b.positions(key, func(bit uint64) bool {
+		b.bits[bit/64] |= 1 << (bit % 64)
+		return true
+	})
+}
+
+func (b *bloomFilter) mayContain(key string) bool {
+	found := true
+	b.positions(key, func(bit uint64) bool {
+		found = b.bits[bit/64]&(1<<(bit%64)) != 0
+		return found
+	})
+	return found
+}
@@ -2,6 +2,7 @@ package coherra
 
 import (
 	"math"
+	"slices"
 
 	"github.com/google/uuid"
 )
@@ -35,6 +36,13 @@ type QuantumMetrics struct {
 	Timestamp          int64
 }
 
+// clone returns a copy of the metrics that shares no slices with the original.
+func (m QuantumMetrics) clone() QuantumMetrics {
+	m.Harmonics = slices.Clone(m.Harmonics)
+	m.CoherenceHistory = slices.Clone(m.CoherenceHistory)
+	return m
+}
+
 // EncryptionMetrics holds encryption-related metrics for quantum security.
 type EncryptionMetrics struct {
 	KeyStrength        int
@@ -57,24 +65,44 @@ type EncryptionMetrics struct {
 }
 
 // GenerateSignature creates a new UUID signature for a mesh node or metric.
+// It panics if the default entropy pool fails; use NewSignature to handle that.
 func GenerateSignature() string {
-	return uuid.New().String()
+	return DefaultOptions().GenerateSignature()
 }
 
-// GlyphMemory tracks historical validation and improvement for each glyph
-type GlyphMemory struct {
-	History []bool
+// NewSignature creates a new UUID signature, returning the entropy pool's error
+// if it fails.
+func NewSignature() (string, error) {
+	return DefaultOptions().NewSignature()
 }
 
-func (gm *GlyphMemory) Improving() bool {
-	if len(gm.History) < 2 {
-		return false
+// GenerateSignature creates a UUID signature from the options' random reader.
+// It panics if the reader fails; use NewSignature to handle that.
+func (o Options) GenerateSignature() string {
+	sig, err := o.NewSignature()
+	if err != nil {
+		panic(err)
 	}
-	// If last two attempts were both valid, or last was valid and previous was invalid
-	return gm.History[len(gm.History)-1] && (!gm.History[len(gm.History)-2] || gm.History[len(gm.History)-2])
+	return sig
+}
+
+// NewSignature creates a UUID signature from the options' random reader,
+// returning the reader's error, for example from an unhealthy entropy pool or
+// an exhausted deterministic source.
+func (o Options) NewSignature() (string, error) {
+	id, err := uuid.NewRandomFromReader(o.random())
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
 }
 
 func InitializeEncryptionMetricsWithGlyph(glyph LyraGlyph) EncryptionMetrics {
+	return DefaultOptions().InitializeEncryptionMetricsWithGlyph(glyph)
+}
+
+// InitializeEncryptionMetricsWithGlyph builds standard encryption metrics using the options' randomness.
+func (o Options) InitializeEncryptionMetricsWithGlyph(glyph LyraGlyph) EncryptionMetrics {
 	return EncryptionMetrics{
 		KeyStrength:        256,
 		EntropyQuality:     0.99,
@@ -82,8 +110,8 @@ func InitializeEncryptionMetricsWithGlyph(glyph LyraGlyph) EncryptionMetrics {
 		CoherenceThreshold: 0.9,
 		EntropyScore:       0.98,
 		KeyLength:          32,
-		Signature:          GenerateSignature(),
-		Harmonics:          GenerateDynamicHarmonics(glyph),
+		Signature:          o.GenerateSignature(),
+		Harmonics:          DefaultGlyphRegistry().Harmonics(glyph),
 		Strength:           0.95,
 		PhaseShift:         math.Pi / 4,
 		EntropyLevel:       10,
@@ -91,6 +119,11 @@ func InitializeEncryptionMetricsWithGlyph(glyph LyraGlyph) EncryptionMetrics {
 }
 
 func InitializeHighSecurityEncryptionMetricsWithGlyph(glyph LyraGlyph) EncryptionMetrics {
+	return DefaultOptions().InitializeHighSecurityEncryptionMetricsWithGlyph(glyph)
+}
+
+// InitializeHighSecurityEncryptionMetricsWithGlyph builds high-security encryption metrics using the options' randomness.
+func (o Options) InitializeHighSecurityEncryptionMetricsWithGlyph(glyph LyraGlyph) EncryptionMetrics {
 	return EncryptionMetrics{
 		KeyStrength:        256,
 		EntropyQuality:     0.99,
@@ -98,35 +131,46 @@ func InitializeHighSecurityEncryptionMetricsWithGlyph(glyph LyraGlyph) Encryptio
 		CoherenceThreshold: 0.90,
 		EntropyScore:       0.98,
 		KeyLength:          4096,
-		Signature:          GenerateSignature(),
-		Harmonics:          GenerateDynamicHarmonics(glyph),
+		Signature:          o.GenerateSignature(),
+		Harmonics:          DefaultGlyphRegistry().Harmonics(glyph),
 		Strength:           0.95,
 		PhaseShift:         math.Pi / 4,
 		EntropyLevel:       10,
 	}
 }
 
+// InitializeQuantumMetricsWithGlyph builds metrics carrying the glyph's timestamp as it is.
 func InitializeQuantumMetricsWithGlyph(glyph LyraGlyph) QuantumMetrics {
+	return DefaultOptions().quantumMetrics(glyph, glyph.Timestamp)
+}
+
+// InitializeQuantumMetricsWithGlyph builds metrics using the options' randomness.
+// A glyph without a timestamp is stamped with the options' clock.
+func (o Options) InitializeQuantumMetricsWithGlyph(glyph LyraGlyph) QuantumMetrics {
+	return o.quantumMetrics(glyph, o.timestamp(glyph.Timestamp))
+}
+
+func (o Options) quantumMetrics(glyph LyraGlyph, timestamp int64) QuantumMetrics {
 	return QuantumMetrics{
 		Coherence:          0.99,
 		Phase:              math.Pi / 2,
 		Amplitude:          1.0,
-		Harmonics:          GenerateDynamicHarmonics(glyph),
+		Harmonics:          DefaultGlyphRegistry().Harmonics(glyph),
 		EntropyScore:       0.98,
 		CoherenceThreshold: 0.90,
 		KeyStrength:        256,
 		EntropyQuality:     0.99,
 		QuantumResistance:  0.95,
-		KeyLength:          4096,
-		Signature:          GenerateSignature(),
+		KeyLength:          DefaultKeyLength,
+		Signature:          o.GenerateSignature(),
 		Strength:           0.95,
 		PhaseShift:         math.Pi / 4,
 		EntropyLevel:       10,
 		Pattern:            "default-pattern",
-		MeshNodeID:         GenerateSignature(),
+		MeshNodeID:         o.GenerateSignature(),
 		CoherenceHistory:   []float64{0.99},
 		ValidationScore:    1.0,
 		NodeState:          "active",
-		Timestamp:          glyph.Timestamp,
+		Timestamp:          timestamp,
 	}
 }
@@ -137,7 +137,7 @@ func TestMeshNodeRevocation(t *testing.T) {
 	net := NewMeshNetwork()
 	net.AddNode(node)
 	net.RevokeNode(node.ID, "test reason")
-	revoked := net.Nodes[node.ID]
+	revoked, _ := net.GetNode(node.ID)
 	if revoked.State != "revoked" {
 		t.Errorf("Node state not revoked: got %s", revoked.State)
 	}
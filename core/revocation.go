@@ -0,0 +1,113 @@
+// revocation.go - Scoped revocation spreading outward over the mesh topology
+package coherra
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RevocationPolicy controls how far a revocation spreads from a compromised node.
+//
+// The origin has suspicion 1. Crossing a link multiplies suspicion by the link's
+// trust and by TrustDecay, so neighbors that trusted the origin heavily are the
+// most suspect. A node is revoked when its suspicion reaches Threshold and it is
+// at most MaxDepth links from the origin.
+type RevocationPolicy struct {
+	MaxDepth   int
+	TrustDecay float64
+	Threshold  float64
+}
+
+// DefaultRevocationPolicy revokes direct neighbors linked with trust of at least 0.8.
+var DefaultRevocationPolicy = RevocationPolicy{MaxDepth: 1, TrustDecay: 0.5, Threshold: 0.4}
+
+// RevocationEntry explains the outcome for one node reached by a revocation.
+type RevocationEntry struct {
+	NodeID    string
+	Depth     int
+	Via       string `json:",omitempty"`
+	Suspicion float64
+	Reason    string
+}
+
+// RevocationReport lists the nodes a PropagateRevocation call revoked and the
+// reachable nodes it spared, ordered by depth and then ID.
+type RevocationReport struct {
+	Origin  string
+	Policy  RevocationPolicy
+	Revoked []RevocationEntry
+	Spared  []RevocationEntry
+}
+
+// PropagateRevocation revokes nodeID and spreads the revocation over the
+// topology according to policy. Nodes that are already revoked keep their
+// original reason but still pass suspicion on to their neighbors.
+func (net *MeshNetwork) PropagateRevocation(nodeID, reason string, policy RevocationPolicy) (RevocationReport, error) {
+	net.mu.Lock()
+	defer net.unlock()
+	if _, ok := net.nodes[nodeID]; !ok {
+		return RevocationReport{}, ErrNodeNotFound
+	}
+
+	// Each round spreads from the suspicion the frontier had when the round
+	// started, in ID order, so a node is never expanded beyond MaxDepth and the
+	// report does not depend on map order. Ties go to the lower Via ID.
+	best := map[string]RevocationEntry{nodeID: {NodeID: nodeID, Suspicion: 1, Reason: reason}}
+	frontier := []string{nodeID}
+	for depth := 1; depth <= policy.MaxDepth && len(frontier) > 0; depth++ {
+		prev := maps.Clone(best)
+		var next []string
+		for _, from := range frontier {
+			for _, to := range net.neighborsLocked(from) {
+				s := prev[from].Suspicion * net.edges[from][to].Trust * policy.TrustDecay
+				if cur, ok := best[to]; ok && cur.Suspicion >= s {
+					continue
+				}
+				best[to] = RevocationEntry{NodeID: to, Depth: depth, Via: from, Suspicion: s}
+				if s >= policy.Threshold && !slices.Contains(next, to) {
+					next = append(next, to)
+				}
+			}
+		}
+		slices.Sort(next)
+		frontier = next
+	}
+
+	report := RevocationReport{Origin: nodeID, Policy: policy}
+	for id, entry := range best {
+		node := net.nodes[id]
+		if id != nodeID {
+			entry.Reason = fmt.Sprintf("Linked to revoked node %s with suspicion %.3f", entry.Via, entry.Suspicion)
+		}
+		if id != nodeID && entry.Suspicion < policy.Threshold {
+			entry.Reason = fmt.Sprintf("Suspicion %.3f below threshold %.3f", entry.Suspicion, policy.Threshold)
+			report.Spared = append(report.Spared, entry)
+			continue
+		}
+		code := RevocationPropagated
+		if id == nodeID {
+			code = RevocationUnspecified
+		}
+		net.recordRevocationLocked(id, code, entry.Reason)
+		if node.State == NodeStateRevoked {
+			entry.Reason = node.RevocationReason
+		} else {
+			net.revokeLocked(node, entry.Reason)
+		}
+		report.Revoked = append(report.Revoked, entry)
+	}
+	sortRevocationEntries(report.Revoked)
+	sortRevocationEntries(report.Spared)
+	return report, nil
+}
+
+func sortRevocationEntries(entries []RevocationEntry) {
+	slices.SortFunc(entries, func(a, b RevocationEntry) int {
+		if a.Depth != b.Depth {
+			return a.Depth - b.Depth
+		}
+		return strings.Compare(a.NodeID, b.NodeID)
+	})
+}
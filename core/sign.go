@@ -0,0 +1,218 @@
+// sign.go - Detached signatures for QALX outputs
+package coherra
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+	"sync"
+)
+
+// SignatureAlgorithm identifies the scheme used to produce a detached signature.
+type SignatureAlgorithm string
+
+// SignatureEd25519 is the default QALX signature algorithm.
+const SignatureEd25519 SignatureAlgorithm = "ed25519"
+
+// signatureDomain separates QALX signatures from any other use of the same key.
+const signatureDomain = "QALX-SIG-v1"
+
+var (
+	ErrInvalidSignature          = &QALXError{"Invalid QALX signature"}
+	ErrMalformedSignature        = &QALXError{"Malformed QALX signature"}
+	ErrSignatureAlgorithm        = &QALXError{"Signature algorithm mismatch"}
+	ErrSignatureNodeMismatch     = &QALXError{"Signature was not issued by the expected mesh node"}
+	ErrUnknownSignatureAlgorithm = &QALXError{"Unknown signature algorithm"}
+	ErrInvalidPublicKey          = &QALXError{"Invalid public key for signature algorithm"}
+	ErrInvalidSigningKey         = &QALXError{"Invalid private key for signature algorithm"}
+)
+
+// Signer produces detached signatures on behalf of a mesh node.
+type Signer interface {
+	Algorithm() SignatureAlgorithm
+	NodeID() string
+	PublicKey() []byte
+	Sign(message []byte) ([]byte, error)
+}
+
+// Verifier checks detached signatures issued by a mesh node.
+type Verifier interface {
+	Algorithm() SignatureAlgorithm
+	NodeID() string
+	Verify(message, signature []byte) error
+}
+
+// VerifierFactory builds a Verifier for a node from its encoded public key.
+type VerifierFactory func(nodeID string, publicKey []byte) (Verifier, error)
+
+var (
+	verifierFactoriesMu sync.RWMutex
+	verifierFactories   = map[SignatureAlgorithm]VerifierFactory{
+		SignatureEd25519: func(nodeID string, publicKey []byte) (Verifier, error) {
+			return NewEd25519Verifier(nodeID, publicKey)
+		},
+	}
+)
+
+// RegisterSignatureAlgorithm makes an additional algorithm available to NewVerifier.
+func RegisterSignatureAlgorithm(alg SignatureAlgorithm, factory VerifierFactory) {
+	verifierFactoriesMu.Lock()
+	defer verifierFactoriesMu.Unlock()
+	verifierFactories[alg] = factory
+}
+
+// NewVerifier returns a Verifier for the given algorithm, node and public key.
+func NewVerifier(alg SignatureAlgorithm, nodeID string, publicKey []byte) (Verifier, error) {
+	verifierFactoriesMu.RLock()
+	factory, ok := verifierFactories[alg]
+	verifierFactoriesMu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownSignatureAlgorithm
+	}
+	return factory(nodeID, publicKey)
+}
+
+// signedMessage binds the payload to the algorithm and issuing node so a
+// signature cannot be replayed under another identity.
+func signedMessage(alg SignatureAlgorithm, nodeID string, data []byte) []byte {
+	msg := make([]byte, 0, len(signatureDomain)+len(alg)+len(nodeID)+len(data)+3)
+	msg = append(msg, signatureDomain...)
+	msg = append(msg, 0)
+	msg = append(msg, alg...)
+	msg = append(msg, 0)
+	msg = append(msg, nodeID...)
+	msg = append(msg, 0)
+	return append(msg, data...)
+}
+
+// Ed25519Signer signs with an Ed25519 key bound to a mesh node ID.
+type Ed25519Signer struct {
+	nodeID string
+	key    ed25519.PrivateKey
+}
+
+// NewEd25519Signer generates a fresh Ed25519 key for the node. A nil reader uses crypto/rand.
+func NewEd25519Signer(node QuantumMeshNode, random io.Reader) (*Ed25519Signer, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+	_, priv, err := ed25519.GenerateKey(random)
+	if err != nil {
+		return nil, err
+	}
+	return &Ed25519Signer{nodeID: node.ID, key: priv}, nil
+}
+
+// NewEd25519SignerFromKey wraps an existing Ed25519 private key for the given node ID.
+func NewEd25519SignerFromKey(nodeID string, key ed25519.PrivateKey) (*Ed25519Signer, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidSigningKey
+	}
+	return &Ed25519Signer{nodeID: nodeID, key: key}, nil
+}
+
+// Algorithm returns SignatureEd25519.
+func (s *Ed25519Signer) Algorithm() SignatureAlgorithm { return SignatureEd25519 }
+
+// NodeID returns the mesh node the signer is bound to.
+func (s *Ed25519Signer) NodeID() string { return s.nodeID }
+
+// PublicKey returns the encoded Ed25519 public key.
+func (s *Ed25519Signer) PublicKey() []byte {
+	return append([]byte(nil), s.key.Public().(ed25519.PublicKey)...)
+}
+
+// Verifier returns the matching Verifier for this signer.
+func (s *Ed25519Signer) Verifier() *Ed25519Verifier {
+	return &Ed25519Verifier{nodeID: s.nodeID, key: s.key.Public().(ed25519.PublicKey)}
+}
+
+// Sign signs the message bound to the signer's node identity.
+func (s *Ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, signedMessage(SignatureEd25519, s.nodeID, message)), nil
+}
+
+// Ed25519Verifier verifies Ed25519 signatures bound to a mesh node ID.
+type Ed25519Verifier struct {
+	nodeID string
+	key    ed25519.PublicKey
+}
+
+// NewEd25519Verifier creates a verifier from a node ID and raw Ed25519 public key.
+func NewEd25519Verifier(nodeID string, publicKey []byte) (*Ed25519Verifier, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+	return &Ed25519Verifier{nodeID: nodeID, key: append(ed25519.PublicKey(nil), publicKey...)}, nil
+}
+
+// Algorithm returns SignatureEd25519.
+func (v *Ed25519Verifier) Algorithm() SignatureAlgorithm { return SignatureEd25519 }
+
+// NodeID returns the mesh node whose signatures this verifier accepts.
+func (v *Ed25519Verifier) NodeID() string { return v.nodeID }
+
+// Verify checks a raw Ed25519 signature over the node-bound message.
+func (v *Ed25519Verifier) Verify(message, signature []byte) error {
+	if !ed25519.Verify(v.key, signedMessage(SignatureEd25519, v.nodeID, message), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// DetachedSignature is a signature carried separately from the data it covers.
+type DetachedSignature struct {
+	Algorithm SignatureAlgorithm
+	NodeID    string
+	Value     []byte
+}
+
+// String encodes the signature as "<algorithm>.<node id>.<signature>" using unpadded base64url.
+func (s DetachedSignature) String() string {
+	enc := base64.RawURLEncoding
+	return string(s.Algorithm) + "." + enc.EncodeToString([]byte(s.NodeID)) + "." + enc.EncodeToString(s.Value)
+}
+
+// ParseDetachedSignature decodes a signature produced by DetachedSignature.String.
+func ParseDetachedSignature(encoded string) (DetachedSignature, error) {
+	parts := strings.Split(encoded, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return DetachedSignature{}, ErrMalformedSignature
+	}
+	enc := base64.RawURLEncoding
+	nodeID, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return DetachedSignature{}, ErrMalformedSignature
+	}
+	value, err := enc.DecodeString(parts[2])
+	if err != nil || len(value) == 0 {
+		return DetachedSignature{}, ErrMalformedSignature
+	}
+	return DetachedSignature{Algorithm: SignatureAlgorithm(parts[0]), NodeID: string(nodeID), Value: value}, nil
+}
+
+// QALXSign produces an encoded detached signature over data using the node's signer.
+func QALXSign(signer Signer, data []byte) (string, error) {
+	value, err := signer.Sign(data)
+	if err != nil {
+		return "", err
+	}
+	return DetachedSignature{Algorithm: signer.Algorithm(), NodeID: signer.NodeID(), Value: value}.String(), nil
+}
+
+// QALXVerify checks an encoded detached signature against data and the expected node's verifier.
+func QALXVerify(verifier Verifier, data []byte, signature string) error {
+	sig, err := ParseDetachedSignature(signature)
+	if err != nil {
+		return err
+	}
+	if sig.Algorithm != verifier.Algorithm() {
+		return ErrSignatureAlgorithm
+	}
+	if sig.NodeID != verifier.NodeID() {
+		return ErrSignatureNodeMismatch
+	}
+	return verifier.Verify(data, sig.Value)
+}
@@ -0,0 +1,454 @@
+// store.go - Persistent storage for MeshNetwork: append-only log plus snapshots
+package coherra
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrStoreCorrupt = &QALXError{"Mesh store log is corrupt"}
+	ErrStoreClosed  = &QALXError{"Mesh store closed"}
+)
+
+// Store record operations.
+const (
+	StorePutNode     = "put-node"
+	StoreRemoveNode  = "remove-node"
+	StorePutEdge     = "put-edge"
+	StoreRemoveEdge  = "remove-edge"
+	StoreRevocations = "revocations"
+	StorePattern     = "pattern"
+)
+
+// StoreRecord is one change to a persisted MeshNetwork. Every record carries the
+// full new value of what it changes, so replaying a record twice is harmless.
+type StoreRecord struct {
+	Op          string
+	Node        *QuantumMeshNode `json:",omitempty"`
+	NodeID      string           `json:",omitempty"`
+	Edge        *MeshEdge        `json:",omitempty"`
+	Revocations *RevocationList  `json:",omitempty"`
+	Pattern     *PatternRecord   `json:",omitempty"`
+}
+
+// MeshState is everything a Store persists about a MeshNetwork.
+type MeshState struct {
+	Nodes       []QuantumMeshNode
+	Edges       []MeshEdge
+	Revocations *RevocationList `json:",omitempty"`
+	Patterns    []PatternRecord
+}
+
+// Store persists MeshNetwork changes.
+type Store interface {
+	// Append durably records one change.
+	Append(rec StoreRecord) error
+	// Snapshot replaces everything recorded so far with state.
+	Snapshot(state MeshState) error
+	// Load returns the latest snapshot with every later record applied.
+	Load() (MeshState, error)
+	Close() error
+}
+
+// meshStateBuilder applies records on top of a snapshot.
+type meshStateBuilder struct {
+	nodes    map[string]QuantumMeshNode
+	edges    map[[2]string]MeshEdge
+	crl      *RevocationList
+	patterns []PatternRecord
+}
+
+func newMeshStateBuilder(state MeshState) *meshStateBuilder {
+	b := &meshStateBuilder{
+		nodes:    make(map[string]QuantumMeshNode),
+		edges:    make(map[[2]string]MeshEdge),
+		crl:      state.Revocations,
+		patterns: state.Patterns,
+	}
+	for _, node := range state.Nodes {
+		b.nodes[node.ID] = node
+	}
+	for _, e := range state.Edges {
+		b.edges[edgeKey(e)] = e
+	}
+	return b
+}
+
+// edgeKey orders an edge's endpoints so both directions share a key.
+func edgeKey(e MeshEdge) [2]string {
+	if e.From > e.To {
+		return [2]string{e.To, e.From}
+	}
+	return [2]string{e.From, e.To}
+}
+
+func (b *meshStateBuilder) apply(rec StoreRecord) error {
+	switch rec.Op {
+	case StorePutNode:
+		if rec.Node == nil {
+			return ErrStoreCorrupt
+		}
+		b.nodes[rec.Node.ID] = *rec.Node
+	case StoreRemoveNode:
+		delete(b.nodes, rec.NodeID)
+		for k := range b.edges {
+			if k[0] == rec.NodeID || k[1] == rec.NodeID {
+				delete(b.edges, k)
+			}
+		}
+	case StorePutEdge, StoreRemoveEdge:
+		if rec.Edge == nil {
+			return ErrStoreCorrupt
+		}
+		e := *rec.Edge
+		k := edgeKey(e)
+		e.From, e.To = k[0], k[1]
+		if rec.Op == StorePutEdge {
+			b.edges[k] = e
+		} else {
+			delete(b.edges, k)
+		}
+	case StoreRevocations:
+		b.crl = rec.Revocations
+	case StorePattern:
+		if rec.Pattern == nil {
+			return ErrStoreCorrupt
+		}
+		if !slices.Contains(b.patterns, *rec.Pattern) {
+			b.patterns = append(b.patterns, *rec.Pattern)
+		}
+	default:
+		return ErrStoreCorrupt
+	}
+	return nil
+}
+
+func (b *meshStateBuilder) state() MeshState {
+	state := MeshState{Revocations: b.crl, Patterns: b.patterns}
+	for _, node := range b.nodes {
+		state.Nodes = append(state.Nodes, node)
+	}
+	slices.SortFunc(state.Nodes, func(a, b QuantumMeshNode) int { return strings.Compare(a.ID, b.ID) })
+	for _, e := range b.edges {
+		state.Edges = append(state.Edges, e)
+	}
+	slices.SortFunc(state.Edges, func(a, b MeshEdge) int {
+		if c := strings.Compare(a.From, b.From); c != 0 {
+			return c
+		}
+		return strings.Compare(a.To, b.To)
+	})
+	return state
+}
+
+// FileStore keeps a MeshNetwork in a directory as snapshot.json plus a
+// JSON-lines log of later changes. Every append is synced before it returns.
+// A crash can only leave a partial last log line, which is discarded on open.
+type FileStore struct {
+	mu     sync.Mutex
+	dir    string
+	log    *os.File
+	closed bool
+}
+
+const (
+	storeSnapshotFile = "snapshot.json"
+	storeLogFile      = "log.jsonl"
+)
+
+// OpenFileStore opens or creates a store in dir, recovering from a torn final log write.
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	log, err := os.OpenFile(filepath.Join(dir, storeLogFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := recoverLog(log); err != nil {
+		log.Close()
+		return nil, err
+	}
+	return &FileStore{dir: dir, log: log}, nil
+}
+
+// recoverLog truncates an incomplete last line and positions f for appending.
+// A malformed complete line means the log was damaged some other way.
+func recoverLog(f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var good int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var rec StoreRecord
+		if json.Unmarshal(line, &rec) != nil {
+			return ErrStoreCorrupt
+		}
+		good += int64(len(line))
+	}
+	if err := f.Truncate(good); err != nil {
+		return err
+	}
+	_, err := f.Seek(good, io.SeekStart)
+	return err
+}
+
+// Append writes rec to the log and syncs it.
+func (s *FileStore) Append(rec StoreRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	if _, err := s.log.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.log.Sync()
+}
+
+// Snapshot atomically replaces the snapshot with state and then empties the log.
+// The new snapshot is durable before the log is truncated; a crash in between
+// leaves records that are already in the snapshot, which replay harmlessly.
+func (s *FileStore) Snapshot(state MeshState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, storeSnapshotFile), data, 0o600); err != nil {
+		return err
+	}
+	if err := s.log.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.log.Sync()
+}
+
+// Load reads the snapshot and replays the log over it.
+func (s *FileStore) Load() (MeshState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return MeshState{}, ErrStoreClosed
+	}
+	var snapshot MeshState
+	data, err := os.ReadFile(filepath.Join(s.dir, storeSnapshotFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return MeshState{}, err
+	default:
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return MeshState{}, ErrStoreCorrupt
+		}
+	}
+	logData, err := os.ReadFile(filepath.Join(s.dir, storeLogFile))
+	if err != nil {
+		return MeshState{}, err
+	}
+	b := newMeshStateBuilder(snapshot)
+	for _, line := range bytes.Split(logData, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var rec StoreRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return MeshState{}, ErrStoreCorrupt
+		}
+		if err := b.apply(rec); err != nil {
+			return MeshState{}, err
+		}
+	}
+	return b.state(), nil
+}
+
+// Close closes the log file.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.log.Close()
+}
+
+// OpenMeshNetwork restores a network from store and persists every later change to it.
+// Validation outcomes in node memories are persisted by Compact and Close.
+func OpenMeshNetwork(store Store) (*MeshNetwork, error) {
+	return DefaultOptions().OpenMeshNetwork(store)
+}
+
+// OpenMeshNetwork restores a network from store using the options' clock.
+func (o Options) OpenMeshNetwork(store Store) (*MeshNetwork, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	net := o.NewMeshNetwork()
+	for _, node := range state.Nodes {
+		net.nodes[node.ID] = node.clone()
+	}
+	for _, e := range state.Edges {
+		if _, ok := net.nodes[e.From]; !ok {
+			continue
+		}
+		if _, ok := net.nodes[e.To]; !ok {
+			continue
+		}
+		net.setEdge(e)
+		e.From, e.To = e.To, e.From
+		net.setEdge(e)
+	}
+	if state.Revocations != nil {
+		net.crl = state.Revocations.clone()
+	}
+	for _, rec := range state.Patterns {
+		net.patterns.insertLocked(rec)
+	}
+	net.store = store
+	return net, nil
+}
+
+// State returns everything a Store persists about the network.
+func (net *MeshNetwork) State() MeshState {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	return net.stateLocked()
+}
+
+func (net *MeshNetwork) stateLocked() MeshState {
+	state := MeshState{Edges: net.edgesLocked(), Patterns: net.patterns.Patterns()}
+	for _, node := range net.nodes {
+		state.Nodes = append(state.Nodes, node.clone())
+	}
+	slices.SortFunc(state.Nodes, func(a, b QuantumMeshNode) int { return strings.Compare(a.ID, b.ID) })
+	if net.crl != nil {
+		state.Revocations = net.crl.clone()
+	}
+	return state
+}
+
+// Compact writes a snapshot of the network to its store, discarding the log.
+func (net *MeshNetwork) Compact() error {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	return net.compactLocked()
+}
+
+func (net *MeshNetwork) compactLocked() error {
+	if net.store == nil {
+		return nil
+	}
+	if err := net.store.Snapshot(net.stateLocked()); err != nil {
+		return err
+	}
+	net.memoryDirty = false
+	return nil
+}
+
+// Err returns the first error the network hit while persisting changes.
+// Changes keep being applied in memory after a persistence failure.
+func (net *MeshNetwork) Err() error {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	return net.storeErr
+}
+
+// Close closes the network's store, if it has one, first writing a snapshot if
+// validation outcomes were recorded since the last one.
+func (net *MeshNetwork) Close() error {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	if net.store == nil {
+		return nil
+	}
+	var err error
+	if net.memoryDirty {
+		err = net.compactLocked()
+	}
+	if cerr := net.store.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// logLocked queues rec for the store; it is written when the lock is released.
+func (net *MeshNetwork) logLocked(rec StoreRecord) {
+	if net.store != nil {
+		net.records = append(net.records, rec)
+	}
+}
+
+// flushLocked writes queued records, in order, while the write lock is still held.
+func (net *MeshNetwork) flushLocked() {
+	records := net.records
+	net.records = nil
+	if net.crlDirty && net.store != nil && net.crl != nil {
+		records = append(records, StoreRecord{Op: StoreRevocations, Revocations: net.crl.clone()})
+	}
+	net.crlDirty = false
+	for _, rec := range records {
+		if err := net.store.Append(rec); err != nil && net.storeErr == nil {
+			net.storeErr = err
+		}
+	}
+}
+
+// putNodeLocked stores node and queues it for persistence.
+func (net *MeshNetwork) putNodeLocked(node QuantumMeshNode) {
+	net.nodes[node.ID] = node
+	net.logLocked(StoreRecord{Op: StorePutNode, Node: &node})
+}
+
+// RecordPattern registers pattern in the network's PatternRegistry, applying
+// the ValidatePattern rules atomically.
+func (net *MeshNetwork) RecordPattern(pattern string) error {
+	net.mu.Lock()
+	defer net.unlock()
+	if err := net.patterns.Register(pattern); err != nil {
+		return err
+	}
+	if rec, ok := net.patterns.get(pattern); ok {
+		net.logLocked(StoreRecord{Op: StorePattern, Pattern: &rec})
+	}
+	return nil
+}
+
+// PatternHistory returns the patterns recorded with RecordPattern, oldest first.
+func (net *MeshNetwork) PatternHistory() []string {
+	var history []string
+	for _, rec := range net.patterns.Patterns() {
+		history = append(history, rec.Pattern)
+	}
+	return history
+}
@@ -0,0 +1,151 @@
+package coherra
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// store_test.go - FileStore persistence and crash recovery tests
+
+func openTestNetwork(t *testing.T, dir string) *MeshNetwork {
+	t.Helper()
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	net, err := OpenMeshNetwork(store)
+	if err != nil {
+		t.Fatalf("OpenMeshNetwork: %v", err)
+	}
+	t.Cleanup(func() { net.Close() })
+	return net
+}
+
+func TestFileStoreRestart(t *testing.T) {
+	dir := t.TempDir()
+	net := openTestNetwork(t, dir)
+	a, b, c := testMeshNode(t), testMeshNode(t), testMeshNode(t)
+	net.SetRevocationList(NewRevocationList(""))
+	for _, n := range []QuantumMeshNode{a, b, c} {
+		net.AddNode(n)
+	}
+	if err := net.Connect(a.ID, b.ID, 0.8, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := net.Connect(b.ID, c.ID, 0.6, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := net.UpdateCoherenceHistory(a.ID, 0.91, 0.93); err != nil {
+		t.Fatal(err)
+	}
+	if err := net.RecordPattern("glyph-pattern-alpha"); err != nil {
+		t.Fatal(err)
+	}
+	if err := net.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	// Changes after the snapshot live only in the log.
+	net.RevokeNode(c.ID, "compromised")
+	net.Disconnect(a.ID, b.ID)
+	if err := net.Quarantine(b.ID, "drift"); err != nil {
+		t.Fatal(err)
+	}
+	if err := net.RecordPattern("glyph-pattern-beta"); err != nil {
+		t.Fatal(err)
+	}
+	want := net.State()
+	if err := net.Err(); err != nil {
+		t.Fatal(err)
+	}
+	net.Close()
+
+	restored := openTestNetwork(t, dir)
+	if got := restored.State(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Restored state differs:\n got: %+v\nwant: %+v", got, want)
+	}
+	if got, _ := restored.GetNode(c.ID); got.State != NodeStateRevoked || got.RevocationReason != "compromised" {
+		t.Errorf("Revocation not restored: %+v", got)
+	}
+	if _, ok := restored.RevocationList().Lookup(c.ID); !ok {
+		t.Error("Revocation list not restored")
+	}
+	if err := restored.RecordPattern("glyph-pattern-alpha"); err == nil {
+		t.Error("Pattern history not restored")
+	}
+}
+
+func TestFileStoreRecoversTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	net := openTestNetwork(t, dir)
+	node := testMeshNode(t)
+	net.AddNode(node)
+	net.Close()
+
+	logPath := filepath.Join(dir, storeLogFile)
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"Op":"remove-node","NodeID":"`)
+	f.Close()
+
+	restored := openTestNetwork(t, dir)
+	if _, ok := restored.GetNode(node.ID); !ok {
+		t.Fatal("Node lost after torn write")
+	}
+	// The store keeps working after recovery.
+	restored.RemoveNode(node.ID)
+	restored.Close()
+	if again := openTestNetwork(t, dir); again.Len() != 0 {
+		t.Error("Append after recovery was not persisted")
+	}
+}
+
+func TestFileStoreSnapshotThenAppend(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := testMeshNode(t), testMeshNode(t)
+	if err := store.Snapshot(MeshState{Nodes: []QuantumMeshNode{a}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(StoreRecord{Op: StorePutNode, Node: &b}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Store left extra files behind: %v", entries)
+	}
+	reopened, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	state, err := reopened.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Nodes) != 2 {
+		t.Fatalf("Reopened store has %d nodes, want the snapshot's and the logged one", len(state.Nodes))
+	}
+}
+
+func TestFileStoreRejectsCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, storeLogFile), []byte("not json\n{}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(dir); err != ErrStoreCorrupt {
+		t.Errorf("Expected ErrStoreCorrupt, got %v", err)
+	}
+}
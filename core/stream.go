@@ -0,0 +1,303 @@
+// stream.go - Chunked streaming encryption for QALX
+package coherra
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Stream format constants.
+//
+// A stream is a header followed by AEAD chunks. Every chunk except the last
+// carries exactly ChunkSize plaintext bytes; the last carries 0..ChunkSize bytes
+// and is sealed with the final flag set, so truncation and reordering are detected.
+// Each chunk nonce is NoncePrefix || uint32 counter || final flag.
+const (
+	StreamVersion          = 1
+	DefaultStreamChunkSize = 64 * 1024
+	MaxStreamChunkSize     = 16 * 1024 * 1024
+	StreamNoncePrefixSize  = 7
+)
+
+var streamMagic = []byte("QALX")
+
+const streamKeyDomain = "QALX-STREAM-v1"
+
+var (
+	ErrStreamHeader    = &QALXError{"Invalid QALX stream header"}
+	ErrStreamVersion   = &QALXError{"Unsupported QALX stream version"}
+	ErrStreamTruncated = &QALXError{"QALX stream truncated"}
+	ErrStreamChunkSize = &QALXError{"Invalid QALX stream chunk size"}
+	ErrStreamTooLong   = &QALXError{"QALX stream exceeds maximum chunk count"}
+	ErrStreamClosed    = &QALXError{"QALX stream already closed"}
+)
+
+// StreamHeader describes an encrypted stream. It is authenticated with every chunk.
+type StreamHeader struct {
+	Version     uint8
+	Algorithm   AEADAlgorithm
+	Glyph       LyraGlyph
+	ChunkSize   uint32
+	NoncePrefix [StreamNoncePrefixSize]byte
+}
+
+// MarshalBinary encodes the header in its on-disk form.
+func (h *StreamHeader) MarshalBinary() ([]byte, error) {
+	if len(h.Algorithm) > math.MaxUint8 || len(h.Glyph.Emotion) > math.MaxUint16 {
+		return nil, ErrStreamHeader
+	}
+	b := append([]byte(nil), streamMagic...)
+	b = append(b, h.Version, uint8(len(h.Algorithm)))
+	b = append(b, h.Algorithm...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(h.Glyph.Emotion)))
+	b = append(b, h.Glyph.Emotion...)
+	b = binary.BigEndian.AppendUint64(b, math.Float64bits(h.Glyph.Intensity))
+	b = binary.BigEndian.AppendUint64(b, math.Float64bits(h.Glyph.EthicsScore))
+	b = binary.BigEndian.AppendUint64(b, uint64(h.Glyph.Timestamp))
+	b = binary.BigEndian.AppendUint32(b, h.ChunkSize)
+	return append(b, h.NoncePrefix[:]...), nil
+}
+
+// readStreamHeader parses a header and returns it with its raw encoding.
+func readStreamHeader(r io.Reader) (*StreamHeader, []byte, error) {
+	var raw bytes.Buffer
+	tr := io.TeeReader(r, &raw)
+	fixed := make([]byte, len(streamMagic)+2)
+	if _, err := io.ReadFull(tr, fixed); err != nil {
+		return nil, nil, ErrStreamHeader
+	}
+	if !bytes.Equal(fixed[:len(streamMagic)], streamMagic) {
+		return nil, nil, ErrStreamHeader
+	}
+	h := &StreamHeader{Version: fixed[len(streamMagic)]}
+	if h.Version != StreamVersion {
+		return nil, nil, ErrStreamVersion
+	}
+	alg := make([]byte, fixed[len(streamMagic)+1])
+	if _, err := io.ReadFull(tr, alg); err != nil {
+		return nil, nil, ErrStreamHeader
+	}
+	h.Algorithm = AEADAlgorithm(alg)
+	var emotionLen uint16
+	if err := binary.Read(tr, binary.BigEndian, &emotionLen); err != nil {
+		return nil, nil, ErrStreamHeader
+	}
+	emotion := make([]byte, emotionLen)
+	if _, err := io.ReadFull(tr, emotion); err != nil {
+		return nil, nil, ErrStreamHeader
+	}
+	var fields struct {
+		Intensity, EthicsScore uint64
+		Timestamp              int64
+		ChunkSize              uint32
+		NoncePrefix            [StreamNoncePrefixSize]byte
+	}
+	if err := binary.Read(tr, binary.BigEndian, &fields); err != nil {
+		return nil, nil, ErrStreamHeader
+	}
+	h.Glyph = LyraGlyph{
+		Emotion:     string(emotion),
+		Intensity:   math.Float64frombits(fields.Intensity),
+		EthicsScore: math.Float64frombits(fields.EthicsScore),
+		Timestamp:   fields.Timestamp,
+	}
+	h.ChunkSize = fields.ChunkSize
+	h.NoncePrefix = fields.NoncePrefix
+	if h.ChunkSize == 0 || h.ChunkSize > MaxStreamChunkSize {
+		return nil, nil, ErrStreamChunkSize
+	}
+	return h, raw.Bytes(), nil
+}
+
+// newStreamAEAD derives a per-stream key from the QALX key and the encoded header.
+func newStreamAEAD(key []byte, h *StreamHeader, rawHeader []byte) (cipher.AEAD, error) {
+	streamKey, err := hkdf.Key(sha512.New, key, rawHeader, streamKeyDomain, MinAEADKeySize)
+	if err != nil {
+		return nil, err
+	}
+	return NewAEAD(h.Algorithm, streamKey)
+}
+
+func streamNonce(prefix [StreamNoncePrefixSize]byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, 0, StreamNoncePrefixSize+5)
+	nonce = append(nonce, prefix[:]...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+type streamEncrypter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  *StreamHeader
+	ad      []byte
+	buf     []byte
+	counter uint32
+	closed  bool
+}
+
+// NewStreamEncrypter writes a stream header to w and returns a writer that encrypts
+// everything written to it. Close must be called to emit the final chunk.
+func NewStreamEncrypter(w io.Writer, key []byte, alg AEADAlgorithm, glyph LyraGlyph, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	if chunkSize < 0 || chunkSize > MaxStreamChunkSize {
+		return nil, ErrStreamChunkSize
+	}
+	h := &StreamHeader{Version: StreamVersion, Algorithm: alg, Glyph: glyph, ChunkSize: uint32(chunkSize)}
+	if _, err := rand.Read(h.NoncePrefix[:]); err != nil {
+		return nil, err
+	}
+	raw, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newStreamAEAD(key, h, raw)
+	if err != nil {
+		return nil, err
+	}
+	if aead.NonceSize() != StreamNoncePrefixSize+5 {
+		return nil, ErrUnknownAEADAlgorithm
+	}
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+	return &streamEncrypter{w: w, aead: aead, header: h, ad: raw, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (s *streamEncrypter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		// Only flush a full buffer once more data arrives, so the last chunk is always sealed as final.
+		if len(s.buf) == cap(s.buf) {
+			if err := s.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (s *streamEncrypter) flush(final bool) error {
+	if s.counter == math.MaxUint32 {
+		return ErrStreamTooLong
+	}
+	out := s.aead.Seal(nil, streamNonce(s.header.NoncePrefix, s.counter, final), s.buf, s.ad)
+	s.counter++
+	s.buf = s.buf[:0]
+	_, err := s.w.Write(out)
+	return err
+}
+
+// Close seals the final chunk. It does not close the underlying writer.
+func (s *streamEncrypter) Close() error {
+	if s.closed {
+		return ErrStreamClosed
+	}
+	s.closed = true
+	return s.flush(true)
+}
+
+type streamDecrypter struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  *StreamHeader
+	ad      []byte
+	chunk   []byte
+	plain   []byte
+	counter uint32
+	done    bool
+}
+
+// NewStreamDecrypter reads and validates a stream header from r and returns a
+// reader yielding authenticated plaintext. A truncated stream returns ErrStreamTruncated.
+func NewStreamDecrypter(r io.Reader, key []byte) (io.Reader, *StreamHeader, error) {
+	br := bufio.NewReader(r)
+	h, raw, err := readStreamHeader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newStreamAEAD(key, h, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if aead.NonceSize() != StreamNoncePrefixSize+5 {
+		return nil, nil, ErrUnknownAEADAlgorithm
+	}
+	return &streamDecrypter{
+		r:      br,
+		aead:   aead,
+		header: h,
+		ad:     raw,
+		chunk:  make([]byte, int(h.ChunkSize)+aead.Overhead()),
+	}, h, nil
+}
+
+func (s *streamDecrypter) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+func (s *streamDecrypter) next() error {
+	n, err := io.ReadFull(s.r, s.chunk)
+	final := false
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	case err != nil:
+		return err
+	default:
+		if _, perr := s.r.Peek(1); errors.Is(perr, io.EOF) {
+			final = true
+		} else if perr != nil {
+			return perr
+		}
+	}
+	if n < s.aead.Overhead() {
+		return ErrStreamTruncated
+	}
+	plain, err := s.aead.Open(nil, streamNonce(s.header.NoncePrefix, s.counter, final), s.chunk[:n], s.ad)
+	if err != nil {
+		// A chunk that only authenticates as non-final means the stream was cut short.
+		if final {
+			if _, nerr := s.aead.Open(nil, streamNonce(s.header.NoncePrefix, s.counter, false), s.chunk[:n], s.ad); nerr == nil {
+				return ErrStreamTruncated
+			}
+		}
+		return ErrDecryptionFailed
+	}
+	if s.counter == math.MaxUint32 && !final {
+		return ErrStreamTooLong
+	}
+	s.counter++
+	s.plain = plain
+	s.done = final
+	return nil
+}
@@ -0,0 +1,82 @@
+package coherra
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// stream_test.go - Streaming encryption round-trip and tamper tests
+
+func encryptStream(t *testing.T, key, data []byte, alg AEADAlgorithm, chunkSize int) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	glyph := LyraGlyph{Emotion: "trust", Intensity: 0.9, EthicsScore: 0.95, Timestamp: 1234567890}
+	w, err := NewStreamEncrypter(&out, key, alg, glyph, chunkSize)
+	if err != nil {
+		t.Fatalf("NewStreamEncrypter: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{9}, 64)
+	for _, alg := range []AEADAlgorithm{AEADAES256GCM, AEADChaCha20Poly1305} {
+		for _, size := range []int{0, 1, 15, 16, 17, 64, 1000} {
+			data := bytes.Repeat([]byte{byte(size)}, size)
+			enc := encryptStream(t, key, data, alg, 16)
+			r, h, err := NewStreamDecrypter(bytes.NewReader(enc), key)
+			if err != nil {
+				t.Fatalf("%s/%d: NewStreamDecrypter: %v", alg, size, err)
+			}
+			if h.Algorithm != alg || h.Glyph.Emotion != "trust" || h.ChunkSize != 16 {
+				t.Errorf("%s/%d: unexpected header %+v", alg, size, h)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("%s/%d: round trip failed: %v", alg, size, err)
+			}
+		}
+	}
+}
+
+func TestStreamDetectsTamperingAndTruncation(t *testing.T) {
+	key := bytes.Repeat([]byte{9}, 64)
+	data := bytes.Repeat([]byte("abcdefgh"), 10)
+	enc := encryptStream(t, key, data, AEADAES256GCM, 16)
+	chunk := 16 + 16
+
+	// Drop the final chunk: the previous chunk is not marked final.
+	r, _, err := NewStreamDecrypter(bytes.NewReader(enc[:len(enc)-chunk]), key)
+	if err != nil {
+		t.Fatalf("NewStreamDecrypter: %v", err)
+	}
+	if _, err := io.ReadAll(r); err != ErrStreamTruncated {
+		t.Errorf("Expected ErrStreamTruncated, got %v", err)
+	}
+
+	tampered := append([]byte(nil), enc...)
+	tampered[len(tampered)-chunk*2] ^= 1
+	r, _, _ = NewStreamDecrypter(bytes.NewReader(tampered), key)
+	if _, err := io.ReadAll(r); err != ErrDecryptionFailed {
+		t.Errorf("Expected ErrDecryptionFailed, got %v", err)
+	}
+
+	// Editing the glyph in the header changes the stream key.
+	headerEdit := append([]byte(nil), enc...)
+	headerEdit[len(streamMagic)+2+len(AEADAES256GCM)+2] = 'T'
+	r, _, _ = NewStreamDecrypter(bytes.NewReader(headerEdit), key)
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("Expected failure after header modification")
+	}
+
+	if _, _, err := NewStreamDecrypter(bytes.NewReader([]byte("nope")), key); err != ErrStreamHeader {
+		t.Errorf("Expected ErrStreamHeader, got %v", err)
+	}
+}
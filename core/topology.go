@@ -0,0 +1,260 @@
+// topology.go - Mesh topology: trust-weighted links, paths and path variance
+package coherra
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidEdge = &QALXError{"Invalid mesh edge"}
+	ErrNoPath      = &QALXError{"No path between mesh nodes"}
+)
+
+// MeshEdge is an undirected link between two nodes. Trust is in [0, 1];
+// Latency is the link cost used for shortest paths.
+type MeshEdge struct {
+	From    string
+	To      string
+	Trust   float64
+	Latency time.Duration
+}
+
+// Connect links two nodes, replacing any existing link between them.
+func (net *MeshNetwork) Connect(from, to string, trust float64, latency time.Duration) error {
+	if from == to || math.IsNaN(trust) || trust < 0 || trust > 1 || latency < 0 {
+		return ErrInvalidEdge
+	}
+	net.mu.Lock()
+	defer net.unlock()
+	if _, ok := net.nodes[from]; !ok {
+		return ErrNodeNotFound
+	}
+	if _, ok := net.nodes[to]; !ok {
+		return ErrNodeNotFound
+	}
+	net.markReconfig()
+	edge := MeshEdge{From: from, To: to, Trust: trust, Latency: latency}
+	net.logLocked(StoreRecord{Op: StorePutEdge, Edge: &edge})
+	net.setEdge(edge)
+	edge.From, edge.To = to, from
+	net.setEdge(edge)
+	return nil
+}
+
+func (net *MeshNetwork) setEdge(edge MeshEdge) {
+	if net.edges[edge.From] == nil {
+		net.edges[edge.From] = make(map[string]MeshEdge)
+	}
+	net.edges[edge.From][edge.To] = edge
+}
+
+// Disconnect removes the link between two nodes and reports whether it existed.
+func (net *MeshNetwork) Disconnect(from, to string) bool {
+	net.mu.Lock()
+	defer net.unlock()
+	return net.disconnectLocked(from, to)
+}
+
+func (net *MeshNetwork) disconnectLocked(from, to string) bool {
+	if _, ok := net.edges[from][to]; !ok {
+		return false
+	}
+	net.markReconfig()
+	net.logLocked(StoreRecord{Op: StoreRemoveEdge, Edge: &MeshEdge{From: from, To: to}})
+	delete(net.edges[from], to)
+	delete(net.edges[to], from)
+	if len(net.edges[from]) == 0 {
+		delete(net.edges, from)
+	}
+	if len(net.edges[to]) == 0 {
+		delete(net.edges, to)
+	}
+	return true
+}
+
+// Neighbors returns the IDs linked to a node, in order.
+func (net *MeshNetwork) Neighbors(nodeID string) []string {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	return net.neighborsLocked(nodeID)
+}
+
+func (net *MeshNetwork) neighborsLocked(nodeID string) []string {
+	ids := make([]string, 0, len(net.edges[nodeID]))
+	for id := range net.edges[nodeID] {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Edges returns every link once, with From < To, ordered by endpoints.
+func (net *MeshNetwork) Edges() []MeshEdge {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	return net.edgesLocked()
+}
+
+func (net *MeshNetwork) edgesLocked() []MeshEdge {
+	var edges []MeshEdge
+	for _, links := range net.edges {
+		for _, e := range links {
+			if e.From < e.To {
+				edges = append(edges, e)
+			}
+		}
+	}
+	slices.SortFunc(edges, func(a, b MeshEdge) int {
+		if c := strings.Compare(a.From, b.From); c != 0 {
+			return c
+		}
+		return strings.Compare(a.To, b.To)
+	})
+	return edges
+}
+
+// ShortestPath returns the lowest-latency path between two nodes, including both
+// endpoints, and its total latency.
+func (net *MeshNetwork) ShortestPath(from, to string) ([]string, time.Duration, error) {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	if _, ok := net.nodes[from]; !ok {
+		return nil, 0, ErrNodeNotFound
+	}
+	if _, ok := net.nodes[to]; !ok {
+		return nil, 0, ErrNodeNotFound
+	}
+	paths := net.shortestPathsLocked(from)
+	if _, ok := paths.dist[to]; !ok {
+		return nil, 0, ErrNoPath
+	}
+	path := []string{to}
+	for id := to; id != from; {
+		id = paths.prev[id]
+		path = append(path, id)
+	}
+	slices.Reverse(path)
+	return path, paths.dist[to], nil
+}
+
+// PathVariance is the population variance of hop counts over the shortest paths
+// between every connected pair of nodes. A fully meshed network scores 0.
+func (net *MeshNetwork) PathVariance() float64 {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	return net.pathVarianceLocked()
+}
+
+// pathVarianceLocked returns the path variance, computing it at most once per
+// reconfiguration. It needs at least the read lock.
+func (net *MeshNetwork) pathVarianceLocked() float64 {
+	if v := net.pathVariance.Load(); v != nil {
+		return *v
+	}
+	v := net.computePathVarianceLocked()
+	net.pathVariance.Store(&v)
+	return v
+}
+
+func (net *MeshNetwork) computePathVarianceLocked() float64 {
+	var hops []float64
+	for id := range net.edges {
+		for to, h := range net.shortestPathsLocked(id).hops {
+			if to > id {
+				hops = append(hops, float64(h))
+			}
+		}
+	}
+	if len(hops) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, h := range hops {
+		mean += h
+	}
+	mean /= float64(len(hops))
+	variance := 0.0
+	for _, h := range hops {
+		variance += (h - mean) * (h - mean)
+	}
+	return variance / float64(len(hops))
+}
+
+// AverageTrust returns the mean trust weight over all links, or 1 with no links.
+func (net *MeshNetwork) AverageTrust() float64 {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+	return net.averageTrustLocked()
+}
+
+func (net *MeshNetwork) averageTrustLocked() float64 {
+	edges := net.edgesLocked()
+	if len(edges) == 0 {
+		return 1
+	}
+	sum := 0.0
+	for _, e := range edges {
+		sum += e.Trust
+	}
+	return sum / float64(len(edges))
+}
+
+type shortestPaths struct {
+	dist map[string]time.Duration
+	prev map[string]string
+	hops map[string]int
+}
+
+// shortestPathsLocked runs Dijkstra from src over link latency, breaking ties by hop count.
+func (net *MeshNetwork) shortestPathsLocked(src string) shortestPaths {
+	sp := shortestPaths{
+		dist: map[string]time.Duration{src: 0},
+		prev: make(map[string]string),
+		hops: map[string]int{src: 0},
+	}
+	done := make(map[string]bool)
+	queue := &pathQueue{{id: src}}
+	for queue.Len() > 0 {
+		cur := heap.Pop(queue).(pathItem)
+		if done[cur.id] {
+			continue
+		}
+		done[cur.id] = true
+		for to, e := range net.edges[cur.id] {
+			d, h := cur.dist+e.Latency, cur.hops+1
+			if old, ok := sp.dist[to]; !ok || d < old || (d == old && h < sp.hops[to]) {
+				sp.dist[to], sp.hops[to], sp.prev[to] = d, h, cur.id
+				heap.Push(queue, pathItem{id: to, dist: d, hops: h})
+			}
+		}
+	}
+	return sp
+}
+
+type pathItem struct {
+	id   string
+	dist time.Duration
+	hops int
+}
+
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	return q[i].hops < q[j].hops
+}
+func (q pathQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)   { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,395 @@
+// transport.go - TLS 1.3 transport for mesh propagation between hosts
+package coherra
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Framing: every frame is a 4-byte big-endian length, a 1-byte frame type and a
+// JSON payload. The length covers the type byte and payload. Each request frame
+// is answered by exactly one ack frame.
+// A transport closes inbound connections that send no frame for IdleTimeout, or
+// take longer than FrameTimeout to deliver a frame's body.
+const (
+	MaxFrameSize = 16 * 1024 * 1024
+	FrameTimeout = 30 * time.Second
+	IdleTimeout  = 2 * time.Minute
+)
+
+// Frame types: a node's metrics, coherence values for a node, a signed
+// revocation list, and the ack answering each of them.
+const (
+	frameMetrics   byte = 1
+	frameCoherence byte = 2
+	frameCRL       byte = 3
+	frameAck       byte = 0x7f
+)
+
+var (
+	ErrFrameTooLarge     = &QALXError{"Mesh frame exceeds maximum size"}
+	ErrUnknownFrame      = &QALXError{"Unknown mesh frame type"}
+	ErrTransportTLS      = &QALXError{"Mesh transport requires TLS 1.3"}
+	ErrTransportClosed   = &QALXError{"Mesh transport closed"}
+	ErrTransportListener = &QALXError{"Mesh transport is already listening"}
+	ErrPeerNotAuthorized = &QALXError{"Mesh peer is not authorized for this node"}
+)
+
+// RemoteError carries an error reported by the peer that handled a frame.
+type RemoteError struct {
+	Reason string
+}
+
+// Error returns the error message for RemoteError.
+func (e *RemoteError) Error() string {
+	return e.Reason
+}
+
+type metricsMessage struct {
+	Node QuantumMeshNode
+}
+
+type coherenceMessage struct {
+	NodeID  string
+	History []float64
+}
+
+type ackMessage struct {
+	Error string `json:",omitempty"`
+}
+
+func writeFrame(w io.Writer, kind byte, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if len(body)+1 > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+	buf := make([]byte, 5, 5+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)+1))
+	buf[4] = kind
+	_, err = w.Write(append(buf, body...))
+	return err
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	kind, size, err := readFrameHeader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body, err := readFrameBody(r, size)
+	if err != nil {
+		return 0, nil, err
+	}
+	return kind, body, nil
+}
+
+// readFrameHeader returns the frame type and the payload size.
+func readFrameHeader(r io.Reader) (byte, int, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, 0, err
+	}
+	size := binary.BigEndian.Uint32(hdr[:4])
+	if size == 0 || size > MaxFrameSize {
+		return 0, 0, ErrFrameTooLarge
+	}
+	return hdr[4], int(size - 1), nil
+}
+
+// readFrameBody reads a payload of size bytes. The buffer grows as data arrives,
+// so a peer cannot make the reader allocate a large frame it never sends.
+func readFrameBody(r io.Reader, size int) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return body, nil
+}
+
+// MeshTransport accepts mesh frames over TLS 1.3 and applies them to a local MeshNetwork.
+type MeshTransport struct {
+	network  *MeshNetwork
+	config   *tls.Config
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+	issuers  map[string]Verifier
+
+	idleTimeout  time.Duration
+	frameTimeout time.Duration
+}
+
+// NewMeshTransport creates a transport for network. The config must carry the local
+// certificate, used both for serving and as the client certificate when dialing,
+// and the trust roots; TLS 1.3 is enforced.
+//
+// Unless config sets ClientAuth, peers must present a certificate that verifies
+// against ClientCAs, which defaults to RootCAs. A peer's verified certificate
+// binds it to the mesh node named by its subject common name (see PeerNodeID),
+// and the peer may only share metrics and coherence history for that node.
+// Peers without a verified certificate may only send signed revocation lists.
+func NewMeshTransport(network *MeshNetwork, config *tls.Config) (*MeshTransport, error) {
+	if config == nil || (config.MaxVersion != 0 && config.MaxVersion < tls.VersionTLS13) {
+		return nil, ErrTransportTLS
+	}
+	cfg := config.Clone()
+	cfg.MinVersion = tls.VersionTLS13
+	if cfg.ClientAuth == tls.NoClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if cfg.ClientCAs == nil {
+		cfg.ClientCAs = cfg.RootCAs
+	}
+	return &MeshTransport{
+		network:      network,
+		config:       cfg,
+		conns:        make(map[net.Conn]struct{}),
+		issuers:      make(map[string]Verifier),
+		idleTimeout:  IdleTimeout,
+		frameTimeout: FrameTimeout,
+	}, nil
+}
+
+// TrustIssuer accepts revocation lists signed by the verifier's node.
+func (t *MeshTransport) TrustIssuer(verifier Verifier) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.issuers[verifier.NodeID()] = verifier
+}
+
+// Listen starts accepting peers on addr (e.g. "127.0.0.1:0").
+func (t *MeshTransport) Listen(addr string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrTransportClosed
+	}
+	if t.listener != nil {
+		return ErrTransportListener
+	}
+	ln, err := tls.Listen("tcp", addr, t.config)
+	if err != nil {
+		return err
+	}
+	t.listener = ln
+	t.wg.Add(1)
+	go t.acceptLoop(ln)
+	return nil
+}
+
+// Addr returns the listening address, or nil before Listen.
+func (t *MeshTransport) Addr() net.Addr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Addr()
+}
+
+// Close stops listening, closes inbound connections and waits for handlers to exit.
+func (t *MeshTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	var err error
+	if t.listener != nil {
+		err = t.listener.Close()
+	}
+	for c := range t.conns {
+		c.Close()
+	}
+	t.mu.Unlock()
+	t.wg.Wait()
+	return err
+}
+
+func (t *MeshTransport) acceptLoop(ln net.Listener) {
+	defer t.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			conn.Close()
+			return
+		}
+		t.conns[conn] = struct{}{}
+		t.wg.Add(1)
+		t.mu.Unlock()
+		go t.serve(conn)
+	}
+}
+
+// PeerNodeID returns the mesh node a peer certificate is bound to: its subject common name.
+func PeerNodeID(cert *x509.Certificate) string {
+	return cert.Subject.CommonName
+}
+
+// peerNodeID returns the node bound to the connection's verified client
+// certificate, or "" if the peer presented none.
+func peerNodeID(conn *tls.Conn) string {
+	chains := conn.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return ""
+	}
+	return PeerNodeID(chains[0][0])
+}
+
+func (t *MeshTransport) serve(conn net.Conn) {
+	defer t.wg.Done()
+	defer func() {
+		t.mu.Lock()
+		delete(t.conns, conn)
+		t.mu.Unlock()
+		conn.Close()
+	}()
+	tlsConn := conn.(*tls.Conn)
+	tlsConn.SetDeadline(time.Now().Add(t.frameTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return
+	}
+	peer := peerNodeID(tlsConn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(t.idleTimeout))
+		kind, size, err := readFrameHeader(conn)
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(t.frameTimeout))
+		body, err := readFrameBody(conn, size)
+		if err != nil {
+			return
+		}
+		ack := ackMessage{}
+		if err := t.handle(peer, kind, body); err != nil {
+			ack.Error = err.Error()
+		}
+		conn.SetWriteDeadline(time.Now().Add(t.frameTimeout))
+		if err := writeFrame(conn, frameAck, ack); err != nil {
+			return
+		}
+	}
+}
+
+// handle applies one inbound frame from the peer bound to node peer to the local network.
+func (t *MeshTransport) handle(peer string, kind byte, body []byte) error {
+	switch kind {
+	case frameMetrics:
+		var msg metricsMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return err
+		}
+		if peer == "" || msg.Node.ID != peer {
+			return ErrPeerNotAuthorized
+		}
+		return t.network.mergeRemoteNode(msg.Node)
+	case frameCoherence:
+		var msg coherenceMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return err
+		}
+		if peer == "" || msg.NodeID != peer {
+			return ErrPeerNotAuthorized
+		}
+		return t.network.updateRemoteCoherence(msg.NodeID, msg.History)
+	case frameCRL:
+		l, err := ParseRevocationList(body)
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		verifier, ok := t.issuers[l.Issuer]
+		t.mu.Unlock()
+		if !ok {
+			return ErrUnknownIssuer
+		}
+		_, err = t.network.MergeRevocationList(l, verifier)
+		return err
+	}
+	return ErrUnknownFrame
+}
+
+// MeshPeer is an outbound connection to a remote MeshTransport.
+// Calls are serialized; each waits for the peer's acknowledgement.
+type MeshPeer struct {
+	mu   sync.Mutex
+	conn *tls.Conn
+}
+
+// Dial connects to a remote transport at addr.
+func (t *MeshTransport) Dial(ctx context.Context, addr string) (*MeshPeer, error) {
+	dialer := &tls.Dialer{Config: t.config}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &MeshPeer{conn: conn.(*tls.Conn)}, nil
+}
+
+// SendMetrics shares the state of the node bound to the local certificate.
+// A node new to the peer joins it as pending.
+func (p *MeshPeer) SendMetrics(node QuantumMeshNode) error {
+	return p.request(frameMetrics, metricsMessage{Node: node})
+}
+
+// SendCoherenceHistory appends coherence values to the local certificate's node on the peer.
+func (p *MeshPeer) SendCoherenceHistory(nodeID string, history []float64) error {
+	return p.request(frameCoherence, coherenceMessage{NodeID: nodeID, History: history})
+}
+
+// SendRevocationList distributes a signed revocation list. The peer merges it
+// only if it trusts the list's issuer.
+func (p *MeshPeer) SendRevocationList(l *RevocationList) error {
+	return p.request(frameCRL, l)
+}
+
+// Close closes the connection.
+func (p *MeshPeer) Close() error {
+	return p.conn.Close()
+}
+
+func (p *MeshPeer) request(kind byte, payload any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conn.SetDeadline(time.Now().Add(FrameTimeout))
+	defer p.conn.SetDeadline(time.Time{})
+	if err := writeFrame(p.conn, kind, payload); err != nil {
+		return err
+	}
+	ackKind, body, err := readFrame(p.conn)
+	if err != nil {
+		return err
+	}
+	if ackKind != frameAck {
+		return ErrUnknownFrame
+	}
+	var ack ackMessage
+	if err := json.Unmarshal(body, &ack); err != nil {
+		return err
+	}
+	if ack.Error != "" {
+		return &RemoteError{Reason: ack.Error}
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package coherra
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// transport_test.go - Loopback tests for the TLS mesh transport
+
+// testCA is a certificate authority that issues loopback mesh certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  ed25519.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "qalx-mesh-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: priv, pool: pool}
+}
+
+// config returns a config that serves and dials as nodeID and trusts the CA.
+func (ca *testCA) config(t *testing.T, nodeID string) *tls.Config {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: nodeID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: priv}},
+		RootCAs:      ca.pool,
+	}
+}
+
+func startTransport(t *testing.T, cfg *tls.Config) (*MeshNetwork, *MeshTransport) {
+	t.Helper()
+	network := NewMeshNetwork()
+	tr, err := NewMeshTransport(network, cfg)
+	if err != nil {
+		t.Fatalf("NewMeshTransport: %v", err)
+	}
+	if err := tr.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { tr.Close() })
+	return network, tr
+}
+
+func TestMeshTransportLoopback(t *testing.T) {
+	ca := newTestCA(t)
+	nodeA, nodeB := testMeshNode(t), testMeshNode(t)
+	netA, trA := startTransport(t, ca.config(t, nodeA.ID))
+	netB, trB := startTransport(t, ca.config(t, nodeB.ID))
+	netC, trC := startTransport(t, ca.config(t, "node-c"))
+	netA.AddNode(nodeA)
+	netB.AddNode(nodeB)
+
+	ctx := context.Background()
+	toB, err := trA.Dial(ctx, trB.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer toB.Close()
+	if err := toB.SendMetrics(nodeA); err != nil {
+		t.Fatalf("SendMetrics: %v", err)
+	}
+	gotA, ok := netB.GetNode(nodeA.ID)
+	if !ok || gotA.Pattern != nodeA.Pattern {
+		t.Fatal("Remote node not stored on peer")
+	}
+	// The peer reported its node active, but new remote nodes join pending.
+	if gotA.State != NodeStatePending || len(gotA.StateHistory) != 0 {
+		t.Errorf("Remote node joined as %q with history %+v", gotA.State, gotA.StateHistory)
+	}
+	gotB, _ := netB.GetNode(nodeB.ID)
+
+	if err := toB.SendCoherenceHistory(nodeA.ID, []float64{0.91, 0.92}); err != nil {
+		t.Fatalf("SendCoherenceHistory: %v", err)
+	}
+	gotA, _ = netB.GetNode(nodeA.ID)
+	if gotA.Metrics.Coherence != 0.92 {
+		t.Errorf("Coherence = %v, want 0.92", gotA.Metrics.Coherence)
+	}
+
+	netB.RevokeNode(nodeA.ID, "compromised")
+	// A revoked peer can no longer send metrics or coherence, or resurrect its node.
+	err = toB.SendMetrics(nodeA)
+	if remote, ok := err.(*RemoteError); !ok || remote.Reason != ErrNodeNotActive.Error() {
+		t.Errorf("Expected RemoteError for a revoked peer, got %v", err)
+	}
+	err = toB.SendCoherenceHistory(nodeA.ID, []float64{0.95})
+	if remote, ok := err.(*RemoteError); !ok || remote.Reason != ErrNodeNotActive.Error() {
+		t.Errorf("Expected RemoteError for a revoked peer, got %v", err)
+	}
+	if gotA, _ = netB.GetNode(nodeA.ID); gotA.State != "revoked" || gotA.Metrics.Coherence != 0.92 {
+		t.Errorf("Revoked node state = %q, coherence %v", gotA.State, gotA.Metrics.Coherence)
+	}
+
+	// B relays its own node onward to C.
+	toC, err := trB.Dial(ctx, trC.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer toC.Close()
+	if err := toC.SendMetrics(gotB); err != nil {
+		t.Fatalf("SendMetrics to C: %v", err)
+	}
+	if gotC, ok := netC.GetNode(nodeB.ID); !ok || gotC.Metrics.Coherence != gotB.Metrics.Coherence {
+		t.Error("Relayed node missing or stale on third peer")
+	}
+
+	err = toC.SendCoherenceHistory(nodeA.ID, []float64{0.9})
+	if remote, ok := err.(*RemoteError); !ok || remote.Reason != ErrPeerNotAuthorized.Error() {
+		t.Errorf("Expected RemoteError for another peer's node, got %v", err)
+	}
+	fromA, err := trA.Dial(ctx, trC.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer fromA.Close()
+	err = fromA.SendCoherenceHistory(nodeA.ID, []float64{0.9})
+	if remote, ok := err.(*RemoteError); !ok || remote.Reason != ErrNodeNotFound.Error() {
+		t.Errorf("Expected RemoteError for unknown node, got %v", err)
+	}
+}
+
+func TestMeshTransportPeerAuthorization(t *testing.T) {
+	ca := newTestCA(t)
+	victim, intruder := testMeshNode(t), testMeshNode(t)
+	netB, trB := startTransport(t, ca.config(t, "node-b"))
+	netB.AddNode(victim)
+
+	// A certified peer cannot overwrite a node it is not bound to.
+	_, trA := startTransport(t, ca.config(t, intruder.ID))
+	toB, err := trA.Dial(context.Background(), trB.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer toB.Close()
+	forged := victim
+	forged.Metrics.Coherence = 0.1
+	err = toB.SendMetrics(forged)
+	if remote, ok := err.(*RemoteError); !ok || remote.Reason != ErrPeerNotAuthorized.Error() {
+		t.Errorf("Expected RemoteError for forged node, got %v", err)
+	}
+	if got, _ := netB.GetNode(victim.ID); got.Metrics.Coherence != victim.Metrics.Coherence {
+		t.Error("Forged metrics were applied")
+	}
+
+	// A client without a certificate cannot connect at all.
+	anonymous := &tls.Config{RootCAs: ca.pool, MinVersion: tls.VersionTLS13}
+	conn, err := tls.Dial("tcp", trB.Addr().String(), anonymous)
+	if err == nil {
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if err = writeFrame(conn, frameMetrics, metricsMessage{Node: forged}); err == nil {
+			_, _, err = readFrame(conn)
+		}
+	}
+	if err == nil {
+		t.Error("Peer without a client certificate was served")
+	}
+}
+
+func TestMeshTransportRequiresTLS13(t *testing.T) {
+	cfg := newTestCA(t).config(t, "node-a")
+	cfg.MaxVersion = tls.VersionTLS12
+	if _, err := NewMeshTransport(NewMeshNetwork(), cfg); err != ErrTransportTLS {
+		t.Errorf("Expected ErrTransportTLS, got %v", err)
+	}
+}
+
+func TestMeshTransportRevocationList(t *testing.T) {
+	ca := newTestCA(t)
+	netB, trB := startTransport(t, ca.config(t, "node-b"))
+	_, trA := startTransport(t, ca.config(t, "node-a"))
+	target := testMeshNode(t)
+	netB.AddNode(target)
+
+	signer := testSigner(t)
+	crl := NewRevocationList("")
+	crl.Revoke(RevokedNode{NodeID: target.ID, Code: RevocationKeyCompromise, RevokedAt: time.Now().Unix()})
+	if err := crl.Sign(signer, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	toB, err := trA.Dial(context.Background(), trB.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer toB.Close()
+	err = toB.SendRevocationList(crl)
+	if remote, ok := err.(*RemoteError); !ok || remote.Reason != ErrUnknownIssuer.Error() {
+		t.Errorf("Expected RemoteError for untrusted issuer, got %v", err)
+	}
+	trB.TrustIssuer(signer.Verifier())
+	if err := toB.SendRevocationList(crl); err != nil {
+		t.Fatalf("SendRevocationList: %v", err)
+	}
+	if got, _ := netB.GetNode(target.ID); got.State != "revoked" {
+		t.Error("Distributed revocation list not enforced")
+	}
+}
+
+func TestMeshTransportDropsStalledPeers(t *testing.T) {
+	ca := newTestCA(t)
+	network := NewMeshNetwork()
+	tr, err := NewMeshTransport(network, ca.config(t, "node-b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.idleTimeout, tr.frameTimeout = 100*time.Millisecond, 100*time.Millisecond
+	if err := tr.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	client := ca.config(t, "node-a")
+
+	// An idle peer and a peer that declares a large frame but never sends it are both dropped.
+	for _, prefix := range [][]byte{nil, {0x00, 0xff, 0xff, 0xff, frameMetrics}} {
+		conn, err := tls.Dial("tcp", tr.Addr().String(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write(prefix); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var buf [1]byte
+		_, err = conn.Read(buf[:])
+		if ne, ok := err.(net.Error); err == nil || (ok && ne.Timeout()) {
+			t.Errorf("prefix %x: connection not closed by server: %v", prefix, err)
+		}
+		conn.Close()
+	}
+}
+
+func TestReadFrameTruncatedBody(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, frameAck, ackMessage{Error: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	full := buf.Bytes()
+	if _, _, err := readFrame(bytes.NewReader(full[:len(full)-1])); err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+	kind, body, err := readFrame(bytes.NewReader(full))
+	if err != nil || kind != frameAck || string(body) != `{"Error":"x"}` {
+		t.Errorf("readFrame = %d, %q, %v", kind, body, err)
+	}
+}
@@ -3,3 +3,8 @@ module github.com/MyndScript/QALX
 go 1.24.5
 
 require github.com/google/uuid v1.6.0
+
+require (
+	golang.org/x/crypto v0.41.0
+	golang.org/x/sys v0.35.0 // indirect
+)
@@ -3,13 +3,50 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	coherra "github.com/MyndScript/QALX/core"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "keygen":
+			err = runKeygen(os.Args[2:])
+		case "encrypt":
+			err = runEncrypt(os.Args[2:])
+		case "decrypt":
+			err = runDecrypt(os.Args[2:])
+		default:
+			// Flags without a subcommand select the metrics output.
+			if !strings.HasPrefix(os.Args[1], "-") {
+				usage(os.Args[1])
+				os.Exit(2)
+			}
+			runMetrics()
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "qalx %s: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+	runMetrics()
+}
+
+// usage reports an unknown subcommand and lists the valid invocations.
+func usage(cmd string) {
+	fmt.Fprintf(os.Stderr, "qalx: unknown command %q\n", cmd)
+	fmt.Fprintln(os.Stderr, "usage: qalx [-emotion e] [-intensity i] [-ethics s]")
+	fmt.Fprintln(os.Stderr, "       qalx keygen|encrypt|decrypt [flags]")
+}
+
+func runMetrics() {
 	// Example CLI flags
 	emotion := flag.String("emotion", "joy", "LyraGlyph emotion")
 	intensity := flag.Float64("intensity", 1.0, "LyraGlyph intensity")
@@ -29,3 +66,114 @@ func main() {
 	fmt.Printf("QuantumMetrics: %+v\n", metrics)
 	os.Exit(0)
 }
+
+// glyphFlags registers the LyraGlyph flags shared by the subcommands. The
+// returned function builds the glyph once flags are parsed and rejects
+// invalid values.
+func glyphFlags(fs *flag.FlagSet) func() (coherra.LyraGlyph, error) {
+	emotion := fs.String("emotion", "trust", "LyraGlyph emotion")
+	intensity := fs.Float64("intensity", 1.0, "LyraGlyph intensity")
+	ethics := fs.Float64("ethics", 1.0, "LyraGlyph ethics score")
+	return func() (coherra.LyraGlyph, error) {
+		return coherra.NewLyraGlyph(*emotion, *intensity, *ethics, time.Now().Unix())
+	}
+}
+
+// runKeygen writes a raw QALX key to -out.
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := fs.String("out", "", "key file to write (required)")
+	length := fs.Int("length", coherra.DefaultKeyLength, "key length in bytes")
+	glyph := glyphFlags(fs)
+	fs.Parse(args)
+	if *out == "" {
+		return fmt.Errorf("-out is required")
+	}
+	g, err := glyph()
+	if err != nil {
+		return err
+	}
+	metrics := coherra.InitializeQuantumMetricsWithGlyph(g)
+	metrics.KeyLength = *length
+	key, err := coherra.QALXGenerateSecureKey(metrics, g, coherra.DefaultMeshScore)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, key, 0o600)
+}
+
+// runEncrypt streams -in through chunked AEAD into -out.
+func runEncrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	keyFile := fs.String("key", "", "key file from qalx keygen (required)")
+	in := fs.String("in", "-", "input file, - for stdin")
+	out := fs.String("out", "-", "output file, - for stdout")
+	alg := fs.String("alg", string(coherra.AEADAES256GCM), "aes-256-gcm or chacha20-poly1305")
+	chunk := fs.Int("chunk", coherra.DefaultStreamChunkSize, "plaintext bytes per chunk")
+	glyph := glyphFlags(fs)
+	fs.Parse(args)
+	g, err := glyph()
+	if err != nil {
+		return err
+	}
+	return transform(*keyFile, *in, *out, func(key []byte, r io.Reader, w io.Writer) error {
+		enc, err := coherra.NewStreamEncrypter(w, key, coherra.AEADAlgorithm(*alg), g, *chunk)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(enc, r); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// runDecrypt authenticates and decrypts a stream produced by runEncrypt.
+func runDecrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	keyFile := fs.String("key", "", "key file from qalx keygen (required)")
+	in := fs.String("in", "-", "input file, - for stdin")
+	out := fs.String("out", "-", "output file, - for stdout")
+	fs.Parse(args)
+	return transform(*keyFile, *in, *out, func(key []byte, r io.Reader, w io.Writer) error {
+		dec, _, err := coherra.NewStreamDecrypter(r, key)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, dec)
+		return err
+	})
+}
+
+// transform opens the key, input and output and removes a partial output file on failure.
+func transform(keyFile, in, out string, fn func(key []byte, r io.Reader, w io.Writer) error) error {
+	if keyFile == "" {
+		return fmt.Errorf("-key is required")
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if in != "-" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if out == "-" {
+		return fn(key, r, os.Stdout)
+	}
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := fn(key, r, f); err != nil {
+		f.Close()
+		os.Remove(out)
+		return err
+	}
+	return f.Close()
+}
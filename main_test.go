@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the CLI instead of the tests when re-executed by runCLI.
+func TestMain(m *testing.M) {
+	if os.Getenv("QALX_TEST_CLI") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI runs the test binary as qalx with args and returns its exit code.
+func runCLI(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "QALX_TEST_CLI=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), stderr.String()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0, stderr.String()
+}
+
+func TestCLIRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "key")
+	plain := filepath.Join(dir, "plain")
+	sealed := filepath.Join(dir, "sealed")
+	opened := filepath.Join(dir, "opened")
+	msg := bytes.Repeat([]byte("quantum mesh "), 1000)
+	if err := os.WriteFile(plain, msg, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"keygen", "-out", key},
+		{"encrypt", "-key", key, "-in", plain, "-out", sealed, "-chunk", "4096"},
+		{"decrypt", "-key", key, "-in", sealed, "-out", opened},
+	} {
+		if code, stderr := runCLI(t, args...); code != 0 {
+			t.Fatalf("qalx %s exited %d: %s", args[0], code, stderr)
+		}
+	}
+	got, err := os.ReadFile(opened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Error("Decrypted output does not match the input")
+	}
+}
+
+func TestCLIRejectsInvalidInvocations(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "key")
+	plain := filepath.Join(dir, "plain")
+	sealed := filepath.Join(dir, "sealed")
+	if err := os.WriteFile(plain, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code, stderr := runCLI(t, "keygen", "-out", key); code != 0 {
+		t.Fatalf("keygen exited %d: %s", code, stderr)
+	}
+	if code, _ := runCLI(t, "frobnicate"); code != 2 {
+		t.Errorf("Unknown subcommand exited %d, want 2", code)
+	}
+	if code, _ := runCLI(t, "keygen", "-out", filepath.Join(dir, "bad"), "-ethics", "2"); code != 1 {
+		t.Errorf("keygen with an invalid glyph exited %d, want 1", code)
+	}
+	if code, _ := runCLI(t, "encrypt", "-key", key, "-in", plain, "-out", sealed, "-intensity", "-5"); code != 1 {
+		t.Errorf("encrypt with an invalid glyph exited %d, want 1", code)
+	}
+	if _, err := os.Stat(sealed); !os.IsNotExist(err) {
+		t.Error("encrypt with an invalid glyph left an output file")
+	}
+}